@@ -29,28 +29,56 @@ func main() {
 	collscan := flag.Bool("collscan", false, "list only COLLSCAN (with --loginfo)")
 	cardinality := flag.String("cardinality", "", "check collection cardinality")
 	conn := flag.Int("conn", 10, "nuumber of connections")
-	diag := flag.String("diag", "", "diagnosis of server status or diagnostic.data")
+	conninfo := flag.String("conninfo", "", "connections, client metadata and authentications analytic, --conninfo <log|dir|'glob'>")
+	diag := flag.String("diag", "", "diagnosis of server status or diagnostic.data, correlates slow ops by minute with --loginfo")
 	duration := flag.Int("duration", 5, "load test duration in minutes")
 	drop := flag.Bool("drop", false, "drop examples collection before seeding")
+	examples := flag.Int("examples", 0, "number of slowest log lines listed per query pattern (with --loginfo)")
 	explain := flag.String("explain", "", "explain a query from a JSON doc or a log line")
 	file := flag.String("file", "", "template file for seedibg data")
+	format := flag.String("format", "text", "output format, text, json or csv (csv with --loginfo or --profile, json also with --diag, --indexAdvice or --replinfo)")
+	from := flag.String("from", "", "logs from time, e.g. 2019-03-01T09:30:00Z (with --loginfo)")
+	genTx := flag.String("genTx", "", "generate a --tx transactions file of query patterns, --genTx <output> (with --loginfo)")
+	histogram := flag.Bool("histogram", false, "print ops histogram by time interval (with --loginfo)")
 	index := flag.Bool("index", false, "get indexes info")
+	indexAdvice := flag.String("indexAdvice", "", "recommend indexes of COLLSCAN or poorly indexed patterns of a log or .enc, --indexAdvice <log|.enc> uri")
 	info := flag.Bool("info", false, "get cluster info | Atlas info (atlas://user:key)")
-	loginfo := flag.String("loginfo", "", "log performance analytic")
+	loginfo := flag.String("loginfo", "", "log performance analytic, --loginfo <log|dir|'glob'|.enc> [<log|dir|'glob'|.enc>...] merges members, quote globs")
+	loginfoDiff := flag.String("loginfo-diff", "", "compare query patterns, --loginfo-diff <before> <after> (.enc or log files)")
+	minms := flag.Int("minms", 0, "minimum milliseconds of ops (with --loginfo)")
 	monitor := flag.Bool("monitor", false, "collects server status every 10 seconds")
+	ns := flag.String("ns", "", "comma separated regex of namespaces to include (with --loginfo)")
+	nsExclude := flag.String("nsExclude", "", "comma separated regex of namespaces to exclude (with --loginfo)")
+	ops := flag.String("ops", "", "comma separated commands, e.g. find,aggregate (with --loginfo)")
 	peek := flag.Bool("peek", false, "only collect stats")
 	pipe := flag.String("pipeline", "", "aggregation pipeline")
+	profile := flag.String("profile", "", "system.profile analytic of a database, same report as --loginfo, --profile <db> uri")
+	profileMinutes := flag.Int("profileMinutes", 0, "enable profiling of ops slower than --profileSlowms for minutes before --profile")
+	profileSlowms := flag.Int("profileSlowms", 100, "slowms of profiling enabled by --profileMinutes")
+	redactLog := flag.String("redact-log", "", "redact literal values of a log, --redact-log <log> <output>")
+	replinfo := flag.String("replinfo", "", "replication and election events timeline of members' logs, --replinfo <log|dir|'mongod.log*'> [...]")
+	plan := flag.String("plan", "", "COLLSCAN, IXSCAN or an index, e.g. '{ a: 1 }' (with --loginfo)")
 	schema := flag.Bool("schema", false, "print schema")
 	seed := flag.Bool("seed", false, "seed a database for demo")
 	simonly := flag.Bool("simonly", false, "simulation only mode")
-	span := flag.Int("span", -1, "granunarity for summary")
+	slowms := flag.Int("slowms", 10000, "list ops slower than milliseconds (with --loginfo)")
+	span := flag.Int("span", -1, "granunarity for summary, or seconds of --loginfo time buckets")
+	to := flag.String("to", "", "logs to time, e.g. 2019-03-01T10:30:00Z (with --loginfo)")
 	tps := flag.Int("tps", 300, "number of trasaction per second per connection")
+	top := flag.Int("top", 10, "number of slowest ops listed (with --loginfo)")
 	total := flag.Int("total", 1000, "nuumber of documents to create")
 	tx := flag.String("tx", "", "file with defined transactions")
 	uri := flag.String("uri", "", "MongoDB URI") // orverides connection uri from args
 	ver := flag.Bool("version", false, "print version number")
 	verbose := flag.Bool("v", false, "verbose")
 	webserver := flag.Bool("web", false, "enable web server")
+	webAdminAuth := flag.String("webAdminAuth", "", "web server credentials of mutating endpoints, user:password or token")
+	webAuth := flag.String("webAuth", "", "web server credentials, user:password or token, open dashboard at /?token=<token>")
+	webBind := flag.String("webBind", "localhost", "web server bind address")
+	webOrigins := flag.String("webOrigins", "", "web server allowed CORS origins, comma separated")
+	webPort := flag.Int("webPort", 5408, "web server port number")
+	webTLSCert := flag.String("webTLSCert", "", "web server TLS certificate file")
+	webTLSKey := flag.String("webTLSKey", "", "web server TLS key file")
 
 	flag.Parse()
 	if *uri == "" && len(flag.Args()) > 0 {
@@ -60,6 +88,16 @@ func main() {
 	flag.Visit(func(f *flag.Flag) { flagset[f.Name] = true })
 
 	var err error
+	logFilter := util.NewLogFilter()
+	logFilter.SetCommands(*ops)
+	logFilter.SetMinMilli(*minms)
+	logFilter.SetPlan(*plan)
+	if err = logFilter.SetTimeRange(*from, *to); err != nil {
+		log.Fatal(err)
+	}
+	if err = logFilter.SetNamespaces(*ns, *nsExclude); err != nil {
+		log.Fatal(err)
+	}
 	if *diag != "" {
 		var str string
 		var filenames = []string{*diag}
@@ -67,7 +105,32 @@ func main() {
 			filenames = append(filenames, flag.Args()...)
 		}
 
-		if *webserver == false {
+		if *webserver == false && *loginfo != "" { // correlates slow ops with FTDC state by minute
+			if strings.HasSuffix(*loginfo, ".enc") { // buckets of encoded results are of intervals of their analyses
+				log.Fatal("--loginfo of --diag requires log files, not encoded results")
+			}
+			li := util.NewLogInfo(*loginfo)
+			li.SetCollscan(*collscan)
+			li.SetFilter(logFilter)
+			li.SetInterval(60)
+			if err = li.Load(); err != nil {
+				panic(err)
+			}
+			granularity := 10 // seconds, data points per minute
+			if *span > 0 && *span < 60 {
+				granularity = *span
+			}
+			metrics := sim.NewDiagnosticData(granularity)
+			if err = metrics.DecodeDiagnosticData(filenames); err != nil {
+				panic(err)
+			}
+			list := sim.GetLogDiagCorrelation(li, metrics)
+			if *format == "json" {
+				fmt.Println(mdb.Stringify(list, "", "  "))
+			} else {
+				fmt.Println(sim.GetLogDiagCorrelationSummary(list))
+			}
+		} else if *webserver == false {
 			metrics := sim.NewDiagnosticData(*span)
 			if str, err = metrics.PrintDiagnosticData(filenames); err != nil {
 				panic(err)
@@ -80,6 +143,15 @@ func main() {
 				panic(err)
 			}
 			grafana.SetFTDCSummaryStats(metrics)
+			if *loginfo != "" { // slow ops time series
+				li := util.NewLogInfo(*loginfo)
+				li.SetFilter(logFilter)
+				li.SetInterval(*span)
+				if err = li.Load(); err != nil {
+					panic(err)
+				}
+				grafana.SetLogInfo(li)
+			}
 
 			log.Println("get more granular data points, data point every second.")
 			go func(g *web.Grafana, metrics *sim.DiagnosticData, filenames []string) {
@@ -87,7 +159,14 @@ func main() {
 				metrics.DecodeDiagnosticData(filenames)
 				grafana.SetFTDCDetailStats(metrics)
 			}(grafana, metrics, filenames)
-			web.HTTPServer(5408, metrics, grafana)
+			server := web.NewServer(grafana)
+			server.SetAdminAuth(*webAdminAuth)
+			server.SetAuth(*webAuth)
+			server.SetBind(*webBind)
+			server.SetOrigins(*webOrigins)
+			server.SetPort(*webPort)
+			server.SetTLS(*webTLSCert, *webTLSKey)
+			log.Fatal(server.ListenAndServe())
 		}
 		os.Exit(0)
 	} else if *info == true && strings.Index(*uri, "atlas://") == 0 {
@@ -107,6 +186,7 @@ func main() {
 		os.Exit(0)
 	} else if strings.Index(*loginfo, "atlas://") == 0 {
 		lg := atlas.ParseAtlasURI(*loginfo)
+		lg.SetTimeRange(logFilter.From, logFilter.To)
 		lg.SetVerbose(*verbose)
 		if lg.Error() != "" {
 			panic(lg.Error())
@@ -119,6 +199,11 @@ func main() {
 			fmt.Println("=> processing", filename)
 			var str string
 			li := util.NewLogInfo(filename)
+			li.SetCollscan(*collscan)
+			li.SetExamples(*examples)
+			li.SetFilter(logFilter)
+			li.SetSlowMilli(*slowms)
+			li.SetTop(*top)
 			li.SetVerbose(*verbose)
 			if str, err = li.Analyze(); err != nil {
 				fmt.Println(err)
@@ -127,16 +212,110 @@ func main() {
 			}
 		}
 		os.Exit(0)
-	} else if *loginfo != "" {
+	} else if *conninfo != "" {
 		var str string
-		li := util.NewLogInfo(*loginfo)
-		li.SetCollscan(*collscan)
-		li.SetVerbose(*verbose)
-		if str, err = li.Analyze(); err != nil {
+		filename := *conninfo
+		if pattern := util.GetRotatedLogPattern(append([]string{*conninfo}, flag.Args()...)); pattern != "" {
+			filename = pattern
+		}
+		ci := util.NewConnInfo(filename)
+		ci.SetInterval(*span)
+		if str, err = ci.Analyze(); err != nil {
 			log.Fatal(err)
 		}
 		fmt.Println(str)
-		log.Println("Encoded output written to", li.OutputFilename)
+		os.Exit(0)
+	} else if *replinfo != "" {
+		var str string
+		rt := util.NewReplTimeline(append([]string{*replinfo}, flag.Args()...))
+		if err = rt.Parse(); err != nil {
+			log.Fatal(err)
+		}
+		if str, err = rt.GetTimeline(*format); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(str)
+		os.Exit(0)
+	} else if *redactLog != "" {
+		if len(flag.Args()) == 0 {
+			log.Fatal("usage: keyhole --redact-log <log> <output>")
+		}
+		lr := util.NewLogRedactor(*redactLog)
+		if err = lr.Redact(flag.Arg(0)); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Redacted log written to", flag.Arg(0))
+		os.Exit(0)
+	} else if *loginfoDiff != "" {
+		if len(flag.Args()) == 0 {
+			log.Fatal("usage: keyhole --loginfo-diff <before> <after>")
+		}
+		var infos []*util.LogInfo
+		for _, filename := range []string{*loginfoDiff, flag.Arg(0)} {
+			li := util.NewLogInfo(filename)
+			li.SetCollscan(*collscan)
+			li.SetFilter(logFilter)
+			if err = li.Load(); err != nil {
+				log.Fatal(err)
+			}
+			infos = append(infos, li)
+		}
+		fmt.Println(util.PrintLogInfoDiff(util.DiffLogInfo(infos[0], infos[1])))
+		os.Exit(0)
+	} else if *loginfo != "" {
+		var li *util.LogInfo
+		var infos []*util.LogInfo
+		filenames := append([]string{*loginfo}, flag.Args()...)
+		if pattern := util.GetRotatedLogPattern(filenames); pattern != "" { // an unquoted glob expanded by shell
+			filenames = []string{pattern}
+		}
+		for _, filename := range filenames {
+			li = util.NewLogInfo(filename)
+			li.SetCollscan(*collscan)
+			li.SetExamples(*examples)
+			if *genTx != "" && *examples == 0 { // literals of templates
+				li.SetExamples(1)
+			}
+			li.SetFilter(logFilter)
+			li.SetHistogram(*histogram)
+			li.SetInterval(*span)
+			li.SetSlowMilli(*slowms)
+			li.SetTop(*top)
+			li.SetVerbose(*verbose)
+			if err = li.Load(); err != nil {
+				log.Fatal(err)
+			}
+			infos = append(infos, li)
+		}
+		if len(infos) > 1 {
+			li = util.MergeLogInfo(infos)
+		}
+		if len(infos) > 1 || strings.HasSuffix(*loginfo, ".enc") == false {
+			if err = li.Save(); err != nil {
+				log.Fatal(err)
+			}
+			log.Println("Encoded output written to", li.OutputFilename)
+		}
+		if *genTx != "" {
+			if err = sim.WriteTransactions(*genTx, sim.GetTransactionsFromLogInfo(li)); err != nil {
+				log.Fatal(err)
+			}
+			log.Println("Transactions written to", *genTx)
+		} else if *format == "json" {
+			var str string
+			if str, err = li.GetJSON(); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(str)
+		} else if *format == "csv" {
+			var filenames []string
+			if filenames, err = li.WriteCSV(); err != nil {
+				log.Fatal(err)
+			}
+			log.Println("CSV output written to", strings.Join(filenames, ", "))
+		} else {
+			fmt.Println(li.GetSummary())
+		}
 		os.Exit(0)
 	} else if *ver {
 		fmt.Println("keyhole ver.", version)
@@ -208,6 +387,63 @@ func main() {
 		}
 		ir.Print(m)
 		os.Exit(0)
+	} else if *indexAdvice != "" {
+		li := util.NewLogInfo(*indexAdvice)
+		li.SetFilter(logFilter)
+		if err = li.Load(); err != nil {
+			panic(err)
+		}
+		ia := mdb.NewIndexAdvisor(client)
+		ia.SetVerbose(*verbose)
+		list, e := ia.GetIndexAdvice(li.OpsPatterns)
+		if e != nil {
+			panic(e)
+		}
+		if *format == "json" {
+			fmt.Println(mdb.Stringify(list, "", "  "))
+		} else {
+			fmt.Println(mdb.GetIndexAdviceSummary(list))
+		}
+		os.Exit(0)
+	} else if *profile != "" {
+		li := util.NewLogInfo(*profile + ".profile")
+		li.Host = connString.Hosts[0]
+		li.SetCollscan(*collscan)
+		li.SetExamples(*examples)
+		li.SetFilter(logFilter)
+		li.SetHistogram(*histogram)
+		li.SetInterval(*span)
+		li.SetSlowMilli(*slowms)
+		li.SetTop(*top)
+		li.SetVerbose(*verbose)
+		pr := mdb.NewProfileReader(client)
+		pr.SetMinutes(*profileMinutes)
+		pr.SetSlowMilli(*profileSlowms)
+		pr.SetTimeRange(logFilter.From, logFilter.To)
+		pr.SetVerbose(*verbose)
+		if err = pr.Read(*profile, li); err != nil {
+			panic(err)
+		}
+		if err = li.Save(); err != nil {
+			panic(err)
+		}
+		log.Println("Encoded output written to", li.OutputFilename)
+		if *format == "json" {
+			var str string
+			if str, err = li.GetJSON(); err != nil {
+				panic(err)
+			}
+			fmt.Println(str)
+		} else if *format == "csv" {
+			var filenames []string
+			if filenames, err = li.WriteCSV(); err != nil {
+				panic(err)
+			}
+			log.Println("CSV output written to", strings.Join(filenames, ", "))
+		} else {
+			fmt.Println(li.GetSummary())
+		}
+		os.Exit(0)
 	} else if *schema == true {
 		var str string
 		if str, err = sim.GetSchemaFromCollection(client, connString.Database, *collection); err != nil {
@@ -52,6 +52,16 @@ func (lg *Logs) SetVerbose(verbose bool) {
 	lg.verbose = verbose
 }
 
+// SetTimeRange sets time range of logs to download, zero values keep defaults
+func (lg *Logs) SetTimeRange(from time.Time, to time.Time) {
+	if from.IsZero() == false {
+		lg.startUnix = from.Unix()
+	}
+	if to.IsZero() == false {
+		lg.endUnix = to.Unix()
+	}
+}
+
 // Error returns error string
 func (lg *Logs) Error() string {
 	if lg.err != nil {
@@ -0,0 +1,270 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package mdb
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/simagix/keyhole/sim/util"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// IndexAdvisor recommends indexes of slow query patterns from logs
+type IndexAdvisor struct {
+	client  *mongo.Client
+	verbose bool
+}
+
+// IndexAdviceDoc is a recommended index and query patterns it affects
+type IndexAdviceDoc struct {
+	Command    string   `json:"command"` // createIndexes command
+	Count      int      `json:"count"`   // number of ops
+	Fields     []string `json:"fields"`
+	Key        string   `json:"key"` // e.g. { a: 1, b: -1 }
+	Namespace  string   `json:"ns"`
+	Patterns   []string `json:"patterns"`
+	TotalMilli int      `json:"totalMilli"` // total milliseconds of ops
+}
+
+// ESRIndex is an index by the Equality-Sort-Range rule
+type ESRIndex struct {
+	Equality   []string
+	Sort       []string
+	Directions map[string]int // of sort fields
+	Range      []string
+}
+
+// operators of equality matches, others are ranges
+var equalityOperators = map[string]bool{"$eq": true, "$in": true, "$all": true, "$elemMatch": true}
+
+// NewIndexAdvisor returns index advisor constructor
+func NewIndexAdvisor(client *mongo.Client) *IndexAdvisor {
+	return &IndexAdvisor{client: client}
+}
+
+// SetVerbose -
+func (ia *IndexAdvisor) SetVerbose(verbose bool) {
+	ia.verbose = verbose
+}
+
+// GetIndexAdvice returns deduplicated recommended indexes of COLLSCAN or poorly indexed patterns, ranked by total time
+func (ia *IndexAdvisor) GetIndexAdvice(patterns []util.OpPerformanceDoc) ([]IndexAdviceDoc, error) {
+	ir := NewIndexesReader(ia.client)
+	ir.SetVerbose(ia.verbose)
+	indexesMap := map[string][]IndexStatsDoc{}
+	for _, doc := range patterns {
+		if _, ok := indexesMap[doc.Namespace]; ok {
+			continue
+		}
+		i := strings.Index(doc.Namespace, ".")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid namespace %v", doc.Namespace)
+		}
+		collection := ia.client.Database(doc.Namespace[:i]).Collection(doc.Namespace[i+1:])
+		indexesMap[doc.Namespace] = ir.GetIndexesFromCollection(collection)
+	}
+	return getIndexAdvice(patterns, indexesMap), nil
+}
+
+// getIndexAdvice compares ESR indexes of patterns with existing indexes of namespaces
+func getIndexAdvice(patterns []util.OpPerformanceDoc, indexesMap map[string][]IndexStatsDoc) []IndexAdviceDoc {
+	adviceMap := map[string]IndexAdviceDoc{}
+	for _, doc := range patterns {
+		esr, err := GetESRIndex(doc.Filter)
+		if err != nil || len(esr.GetFields()) == 0 {
+			continue
+		}
+		if doc.Scan != util.COLLSCAN && esr.isCoveredBy(indexesMap[doc.Namespace]) == true {
+			continue
+		}
+		key := esr.GetKey()
+		advice, ok := adviceMap[doc.Namespace+" "+key]
+		if ok == false {
+			advice = IndexAdviceDoc{Fields: esr.GetFields(), Key: key, Namespace: doc.Namespace}
+		}
+		advice.Count += doc.Count
+		advice.TotalMilli += doc.TotalMilli
+		advice.Patterns = append(advice.Patterns, doc.Command+" "+doc.Filter)
+		adviceMap[doc.Namespace+" "+key] = advice
+	}
+
+	list := []IndexAdviceDoc{}
+	for _, advice := range adviceMap {
+		list = append(list, advice)
+	}
+	sort.Slice(list, func(i, j int) bool { return len(list[i].Fields) > len(list[j].Fields) })
+	merged := []IndexAdviceDoc{}
+	for _, advice := range list { // an index also serves its prefixes
+		isMerged := false
+		for i, m := range merged {
+			if m.Namespace == advice.Namespace && strings.HasPrefix(m.Key, strings.TrimSuffix(advice.Key, " }")+",") {
+				merged[i].Count += advice.Count
+				merged[i].TotalMilli += advice.TotalMilli
+				merged[i].Patterns = append(merged[i].Patterns, advice.Patterns...)
+				isMerged = true
+				break
+			}
+		}
+		if isMerged == false {
+			merged = append(merged, advice)
+		}
+	}
+	for i, advice := range merged {
+		merged[i].Command = getCreateIndexesCommand(advice.Namespace, advice.Key)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].TotalMilli == merged[j].TotalMilli {
+			return merged[i].Namespace+merged[i].Key < merged[j].Namespace+merged[j].Key
+		}
+		return merged[i].TotalMilli > merged[j].TotalMilli
+	})
+	return merged
+}
+
+// GetESRIndex returns an index of a query shape, e.g. {a: <number>, b: {$gt: <date>}}, sort: {c: -1}
+func GetESRIndex(filter string) (ESRIndex, error) {
+	esr := ESRIndex{Directions: map[string]int{}}
+	node, extras, err := util.ParseShellDoc(filter)
+	if err != nil {
+		return esr, err
+	}
+	if node.IsDoc() == true {
+		esr.addFilter(node)
+	}
+	for i, key := range extras.Keys {
+		if key != "sort" || extras.Nodes[i].IsDoc() == false {
+			continue
+		}
+		for j, field := range extras.Nodes[i].Keys {
+			if esr.contains(esr.Equality, field) == true || esr.contains(esr.Sort, field) == true {
+				continue
+			}
+			esr.Sort = append(esr.Sort, field)
+			esr.Directions[field] = 1
+			if extras.Nodes[i].Nodes[j].Text == "-1" {
+				esr.Directions[field] = -1
+			}
+		}
+	}
+	ranges := []string{}
+	for _, field := range esr.Range {
+		if esr.contains(esr.Equality, field) == false && esr.contains(esr.Sort, field) == false {
+			ranges = append(ranges, field)
+		}
+	}
+	esr.Range = ranges
+	return esr, nil
+}
+
+// addFilter classifies fields of a filter, clauses of $and are flattened
+func (esr *ESRIndex) addFilter(node *util.ShapeNode) {
+	for i, field := range node.Keys {
+		value := node.Nodes[i]
+		if field == "$and" && value.IsArray() == true {
+			for _, elem := range value.Nodes {
+				if elem.IsDoc() == true {
+					esr.addFilter(elem)
+				}
+			}
+			continue
+		} else if strings.HasPrefix(field, "$") { // $or, $expr, $text, etc.
+			continue
+		}
+		isEquality := value.Type != "regex"
+		if value.IsDoc() == true && len(value.Keys) > 0 && strings.HasPrefix(value.Keys[0], "$") {
+			isEquality = false
+			for _, op := range value.Keys {
+				if equalityOperators[op] == true {
+					isEquality = true
+				}
+			}
+		}
+		if isEquality == true && esr.contains(esr.Equality, field) == false {
+			esr.Equality = append(esr.Equality, field)
+		} else if isEquality == false && esr.contains(esr.Range, field) == false {
+			esr.Range = append(esr.Range, field)
+		}
+	}
+}
+
+func (esr *ESRIndex) contains(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFields returns fields of the index in order
+func (esr *ESRIndex) GetFields() []string {
+	fields := append([]string{}, esr.Equality...)
+	fields = append(fields, esr.Sort...)
+	return append(fields, esr.Range...)
+}
+
+// GetKey returns key of the index, e.g. { a: 1, c: -1, b: 1 }
+func (esr *ESRIndex) GetKey() string {
+	strs := []string{}
+	for _, field := range esr.GetFields() {
+		direction := 1
+		if d, ok := esr.Directions[field]; ok {
+			direction = d
+		}
+		strs = append(strs, fmt.Sprintf("%v: %v", field, direction))
+	}
+	return "{ " + strings.Join(strs, ", ") + " }"
+}
+
+// isCoveredBy returns true if an index begins with equality fields in any order, sort fields, and range fields in any order
+func (esr *ESRIndex) isCoveredBy(indexes []IndexStatsDoc) bool {
+	n := len(esr.GetFields())
+	for _, index := range indexes {
+		if len(index.Fields) < n {
+			continue
+		}
+		e, s := len(esr.Equality), len(esr.Sort)
+		if isSameSet(index.Fields[:e], esr.Equality) && strings.Join(index.Fields[e:e+s], ",") == strings.Join(esr.Sort, ",") &&
+			isSameSet(index.Fields[e+s:n], esr.Range) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSameSet(a []string, b []string) bool {
+	x := append([]string{}, a...)
+	y := append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	return strings.Join(x, ",") == strings.Join(y, ",")
+}
+
+// getCreateIndexesCommand returns a createIndexes command in mongo shell syntax
+func getCreateIndexesCommand(ns string, key string) string {
+	i := strings.Index(ns, ".")
+	name := strings.Replace(strings.Replace(strings.Trim(key, "{ }"), ": ", "_", -1), ", ", "_", -1)
+	return fmt.Sprintf(`db.getSiblingDB("%v").runCommand({ createIndexes: "%v", indexes: [{ key: %v, name: "%v" }] })`,
+		ns[:i], ns[i+1:], key, name)
+}
+
+// GetIndexAdviceSummary returns recommended indexes
+func GetIndexAdviceSummary(list []IndexAdviceDoc) string {
+	var buffer bytes.Buffer
+	if len(list) == 0 {
+		return "No index recommendations."
+	}
+	buffer.WriteString("Recommended indexes, ranked by total time of affected ops:\n")
+	for i, advice := range list {
+		buffer.WriteString(fmt.Sprintf("\n%d. %s %s (ops: %d, total: %s)\n", i+1, advice.Namespace, advice.Key, advice.Count,
+			strings.TrimSpace(util.MilliToTimeString(float64(advice.TotalMilli)))))
+		for _, pattern := range advice.Patterns {
+			buffer.WriteString("   " + pattern + "\n")
+		}
+		buffer.WriteString(advice.Command + "\n")
+	}
+	return buffer.String()
+}
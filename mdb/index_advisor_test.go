@@ -0,0 +1,59 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package mdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/simagix/keyhole/sim/util"
+)
+
+func TestGetESRIndex(t *testing.T) {
+	tests := map[string]string{
+		"{a: <number>, b: {$gt: <date>}}, sort: {c: -1}":                   "{ a: 1, c: -1, b: 1 }",
+		"{$and: [{x: {$in: [<number>]}}, {y: /regex/}], z: {$ne: <bool>}}": "{ x: 1, y: 1, z: 1 }",
+		"{a: {$gte: <number>, $lt: <number>}}, sort: {a: 1}":               "{ a: 1 }",
+		"{$or: [{a: <number>}, {b: <number>}], c: {d: <string>}}":          "{ c: 1 }",
+		"{status: <string>, qty: {$elemMatch: {$gt: <number>}}}, sort: {}": "{ status: 1, qty: 1 }",
+	}
+	for filter, key := range tests {
+		esr, err := GetESRIndex(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if esr.GetKey() != key {
+			t.Fatal(filter, "expected", key, "but got", esr.GetKey())
+		}
+	}
+}
+
+func TestGetIndexAdvice(t *testing.T) {
+	patterns := []util.OpPerformanceDoc{
+		util.OpPerformanceDoc{Command: "find", Namespace: "db.orders", Filter: "{status: <string>}", Scan: util.COLLSCAN, Count: 10, TotalMilli: 1000},
+		util.OpPerformanceDoc{Command: "find", Namespace: "db.orders", Filter: "{status: <string>, date: {$gt: <date>}}", Scan: util.COLLSCAN, Count: 5, TotalMilli: 500},
+		util.OpPerformanceDoc{Command: "find", Namespace: "db.orders", Filter: "{date: {$gt: <date>}, status: <string>}", Scan: util.COLLSCAN, Count: 5, TotalMilli: 500},
+		util.OpPerformanceDoc{Command: "count", Namespace: "db.orders", Filter: "{sku: <string>}", Index: "{ sku: 1, qty: 1 }", Count: 100, TotalMilli: 9000},
+		util.OpPerformanceDoc{Command: "find", Namespace: "db.users", Filter: "{name: <string>}", Index: "{ email: 1 }", Count: 1, TotalMilli: 100},
+	}
+	indexesMap := map[string][]IndexStatsDoc{
+		"db.orders": []IndexStatsDoc{IndexStatsDoc{Key: "{ sku: 1, qty: 1 }", Fields: []string{"sku", "qty"}}},
+		"db.users":  []IndexStatsDoc{IndexStatsDoc{Key: "{ email: 1 }", Fields: []string{"email"}}},
+	}
+	list := getIndexAdvice(patterns, indexesMap)
+	if len(list) != 2 {
+		t.Fatal(list)
+	}
+	if list[0].Key != "{ status: 1, date: 1 }" || list[0].Count != 20 || list[0].TotalMilli != 2000 || len(list[0].Patterns) != 3 {
+		t.Fatal(list[0])
+	}
+	if list[1].Namespace != "db.users" || list[1].Key != "{ name: 1 }" {
+		t.Fatal(list[1])
+	}
+	if list[0].Command != `db.getSiblingDB("db").runCommand({ createIndexes: "orders", indexes: [{ key: { status: 1, date: 1 }, name: "status_1_date_1" }] })` {
+		t.Fatal(list[0].Command)
+	}
+	if str := GetIndexAdviceSummary(list); strings.Index(str, "1. db.orders { status: 1, date: 1 }") < 0 {
+		t.Fatal(str)
+	}
+}
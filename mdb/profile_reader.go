@@ -0,0 +1,131 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/simagix/keyhole/sim/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// default threshold of profiling enabled by the profile reader
+const defaultProfileSlowMilli = 100
+
+// ProfileReader reads system.profile documents of a database
+type ProfileReader struct {
+	client    *mongo.Client
+	from      time.Time
+	minutes   int // profiling enabled for minutes before reading
+	slowMilli int // threshold of enabled profiling
+	to        time.Time
+	verbose   bool
+}
+
+// NewProfileReader returns profile reader constructor
+func NewProfileReader(client *mongo.Client) *ProfileReader {
+	return &ProfileReader{client: client, slowMilli: defaultProfileSlowMilli}
+}
+
+// SetMinutes sets minutes of profiling enabled before reading, the previous level is restored afterward
+func (pr *ProfileReader) SetMinutes(minutes int) {
+	pr.minutes = minutes
+}
+
+// SetSlowMilli sets slowms of enabled profiling, 100 by default
+func (pr *ProfileReader) SetSlowMilli(milli int) {
+	if milli > 0 {
+		pr.slowMilli = milli
+	}
+}
+
+// SetTimeRange sets time range of documents read, either can be zero
+func (pr *ProfileReader) SetTimeRange(from time.Time, to time.Time) {
+	pr.from = from
+	pr.to = to
+}
+
+// SetVerbose -
+func (pr *ProfileReader) SetVerbose(verbose bool) {
+	pr.verbose = verbose
+}
+
+// Read aggregates system.profile documents of a database into query patterns of a LogInfo
+func (pr *ProfileReader) Read(database string, li *util.LogInfo) error {
+	var err error
+	var cur *mongo.Cursor
+	ctx := context.Background()
+	if pr.minutes > 0 {
+		if err = pr.profile(database); err != nil {
+			return err
+		}
+	}
+	filter := bson.D{}
+	ts := bson.D{}
+	if pr.from.IsZero() == false {
+		ts = append(ts, bson.E{Key: "$gte", Value: pr.from})
+	}
+	if pr.to.IsZero() == false {
+		ts = append(ts, bson.E{Key: "$lte", Value: pr.to})
+	}
+	if len(ts) > 0 {
+		filter = append(filter, bson.E{Key: "ts", Value: ts})
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "ts", Value: 1}})
+	if cur, err = pr.client.Database(database).Collection("system.profile").Find(ctx, filter, opts); err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	docs := []bson.D{}
+	for cur.Next(ctx) {
+		var doc bson.D
+		if err = cur.Decode(&doc); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	if pr.verbose == true {
+		log.Println(len(docs), "documents read from", database+".system.profile")
+	}
+	li.ParseProfileDocs(docs)
+	return cur.Err()
+}
+
+// profile enables profiling of slow ops for minutes and restores the previous level and slowms, also if interrupted
+func (pr *ProfileReader) profile(database string) (err error) {
+	var level bson.M
+	ctx := context.Background()
+	db := pr.client.Database(database)
+	if err = db.RunCommand(ctx, bson.D{{Key: "profile", Value: -1}}).Decode(&level); err != nil {
+		return err
+	}
+	if err = db.RunCommand(ctx, bson.D{{Key: "profile", Value: 1}, {Key: "slowms", Value: pr.slowMilli}}).Err(); err != nil {
+		return err
+	}
+	defer func() {
+		restore := bson.D{{Key: "profile", Value: level["was"]}, {Key: "slowms", Value: level["slowms"]}}
+		if rerr := db.RunCommand(ctx, restore).Err(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+	quit := make(chan os.Signal, 2)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	timer := time.NewTimer(time.Duration(pr.minutes) * time.Minute)
+	defer timer.Stop()
+	log.Printf("profiling %s ops slower than %dms for %d minutes\n", database, pr.slowMilli, pr.minutes)
+	select {
+	case sig := <-quit:
+		return fmt.Errorf("profiling of %s interrupted by %v", database, sig)
+	case <-timer.C:
+	}
+	return nil
+}
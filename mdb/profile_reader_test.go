@@ -0,0 +1,21 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package mdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simagix/keyhole/sim/util"
+)
+
+func TestProfileReaderRead(t *testing.T) {
+	client := getMongoClient()
+	defer client.Disconnect(context.Background())
+	pr := NewProfileReader(client)
+	li := util.NewLogInfo(dbName + ".profile")
+	if err := pr.Read(dbName, li); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(li.GetSummary())
+}
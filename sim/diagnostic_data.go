@@ -25,6 +25,7 @@ func getServerStatusDataPoints(attribsMap map[string][]int64, i uint32) mdb.Serv
 	ss.Metrics.QueryExecutor.Scanned = attribsMap["serverStatus/metrics/queryExecutor/scanned"][i]
 	ss.Metrics.QueryExecutor.ScannedObjects = attribsMap["serverStatus/metrics/queryExecutor/scannedObjects"][i]
 	ss.Metrics.Operation.ScanAndOrder = attribsMap["serverStatus/metrics/operation/scanAndOrder"][i]
+	ss.Metrics.Document.Returned = int(attribsMap["serverStatus/metrics/document/returned"][i])
 	if len(attribsMap["serverStatus/opLatencies/commands/latency"]) > 1 { // 3.2 didn't have opLatencies
 		ss.OpLatencies.Commands.Latency = attribsMap["serverStatus/opLatencies/commands/latency"][i]
 		ss.OpLatencies.Commands.Ops = attribsMap["serverStatus/opLatencies/commands/ops"][i]
@@ -49,6 +50,10 @@ func getServerStatusDataPoints(attribsMap map[string][]int64, i uint32) mdb.Serv
 	ss.WiredTiger.Cache.UnmodifiedPagesEvicted = attribsMap["serverStatus/wiredTiger/cache/unmodified pages evicted"][i]
 	ss.WiredTiger.ConcurrentTransactions.Read.Available = attribsMap["serverStatus/wiredTiger/concurrentTransactions/read/available"][i]
 	ss.WiredTiger.ConcurrentTransactions.Write.Available = attribsMap["serverStatus/wiredTiger/concurrentTransactions/write/available"][i]
+	ss.WiredTiger.ConcurrentTransactions.Read.Out = attribsMap["serverStatus/wiredTiger/concurrentTransactions/read/out"][i]
+	ss.WiredTiger.ConcurrentTransactions.Write.Out = attribsMap["serverStatus/wiredTiger/concurrentTransactions/write/out"][i]
+	ss.WiredTiger.ConcurrentTransactions.Read.TotalTickets = attribsMap["serverStatus/wiredTiger/concurrentTransactions/read/totalTickets"][i]
+	ss.WiredTiger.ConcurrentTransactions.Write.TotalTickets = attribsMap["serverStatus/wiredTiger/concurrentTransactions/write/totalTickets"][i]
 	return ss
 }
 
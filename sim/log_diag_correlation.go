@@ -0,0 +1,263 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simagix/keyhole/sim/util"
+)
+
+// thresholds of resource saturations
+const (
+	saturatedCacheDirtyPercent = 20 // eviction_dirty_trigger, application threads evict
+	saturatedCPUPercent        = 90
+	saturatedDiskUtilPercent   = 90
+	saturatedQueued            = 10 // readers and writers queued
+	saturatedTicketsPercent    = 90 // tickets out of total
+)
+
+// minutes of slow ops spikes have slow ops of spikeRatio times the average per minute
+const spikeRatio = 2
+
+// patterns of docs examined per returned are inefficient
+const inefficientExaminedRatio = 100
+
+// number of slow patterns listed per minute
+const minutePatternsListed = 5
+
+// MinuteStatsDoc stores slow ops of a log and the concurrent FTDC state of a minute
+type MinuteStatsDoc struct {
+	CacheDirtyPercent float64            `json:"cacheDirtyPercent"` // max
+	CPUPercent        float64            `json:"cpuPercent"`
+	DiskUtilPercent   float64            `json:"diskUtilPercent"` // of the busiest disk
+	IOWaitPercent     float64            `json:"iowaitPercent"`
+	IsSpike           bool               `json:"spike"`
+	Patterns          []MinutePatternDoc `json:"patterns"` // slowest patterns by total milliseconds
+	Queued            int64              `json:"queued"`   // max readers and writers queued
+	ReadTickets       int64              `json:"readTickets"`
+	ReadTicketsOut    int64              `json:"readTicketsOut"` // max
+	Saturations       []string           `json:"saturations"`
+	SlowMilli         int                `json:"slowMilli"` // total milliseconds of slow ops
+	SlowOps           int                `json:"slowOps"`
+	Time              time.Time          `json:"time"`
+	Verdict           string             `json:"verdict"`
+	WriteTickets      int64              `json:"writeTickets"`
+	WriteTicketsOut   int64              `json:"writeTicketsOut"` // max
+}
+
+// MinutePatternDoc stores slow ops of a query pattern of a minute
+type MinutePatternDoc struct {
+	Command     string `json:"command"`
+	Count       int    `json:"count"`
+	Filter      string `json:"filter"`
+	Inefficient bool   `json:"inefficient"` // COLLSCAN or examines too many docs per returned
+	Namespace   string `json:"ns"`
+	TotalMilli  int    `json:"totalMilli"`
+}
+
+// GetLogDiagCorrelation returns minutes, in time range of FTDC data, of slow ops spikes or of resource saturations
+// with their FTDC state and slowest patterns.  Logs are best analyzed with 60-second or shorter intervals.
+func GetLogDiagCorrelation(li *util.LogInfo, d *DiagnosticData) []MinuteStatsDoc {
+	minutes := getFTDCMinutes(d)
+	for _, doc := range li.OpsPatterns {
+		inefficient := doc.Scan == util.COLLSCAN || doc.DocsExaminedPerReturned() >= inefficientExaminedRatio
+		patterns := map[int64]MinutePatternDoc{}
+		for t, bucket := range doc.Buckets {
+			t -= t % 60
+			p := patterns[t]
+			p.Count += bucket.Count
+			p.TotalMilli += bucket.TotalMilli
+			patterns[t] = p
+		}
+		for t, p := range patterns {
+			m, ok := minutes[t]
+			if ok == false {
+				continue
+			}
+			p.Command, p.Filter, p.Inefficient, p.Namespace = doc.Command, doc.Filter, inefficient, doc.Namespace
+			m.Patterns = append(m.Patterns, p)
+			m.SlowOps += p.Count
+			m.SlowMilli += p.TotalMilli
+			minutes[t] = m
+		}
+	}
+
+	total, n := 0, 0
+	for _, m := range minutes {
+		if m.SlowOps > 0 {
+			total += m.SlowOps
+			n++
+		}
+	}
+	list := []MinuteStatsDoc{}
+	for _, m := range minutes {
+		m.IsSpike = n > 0 && m.SlowOps > 1 && m.SlowOps*n >= spikeRatio*total
+		m.Saturations = getSaturations(m)
+		if m.IsSpike == false && len(m.Saturations) == 0 {
+			continue
+		}
+		sort.Slice(m.Patterns, func(i, j int) bool { return m.Patterns[i].TotalMilli > m.Patterns[j].TotalMilli })
+		m.Verdict = getCorrelationVerdict(m)
+		if len(m.Patterns) > minutePatternsListed {
+			m.Patterns = m.Patterns[:minutePatternsListed]
+		}
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Time.Before(list[j].Time) })
+	return list
+}
+
+// getFTDCMinutes returns FTDC state by epoch seconds of minutes
+func getFTDCMinutes(d *DiagnosticData) map[int64]MinuteStatsDoc {
+	minutes := map[int64]MinuteStatsDoc{}
+	for _, ss := range d.ServerStatusList {
+		if ss.LocalTime.IsZero() {
+			continue
+		}
+		t := ss.LocalTime.Unix() - ss.LocalTime.Unix()%60
+		m, ok := minutes[t]
+		if ok == false {
+			m = MinuteStatsDoc{Time: time.Unix(t, 0).UTC()}
+		}
+		if queued := ss.GlobalLock.CurrentQueue.Readers + ss.GlobalLock.CurrentQueue.Writers; queued > m.Queued {
+			m.Queued = queued
+		}
+		read, write := ss.WiredTiger.ConcurrentTransactions.Read, ss.WiredTiger.ConcurrentTransactions.Write
+		if read.Out > m.ReadTicketsOut {
+			m.ReadTicketsOut = read.Out
+		}
+		if write.Out > m.WriteTicketsOut {
+			m.WriteTicketsOut = write.Out
+		}
+		m.ReadTickets, m.WriteTickets = read.Available+read.Out, write.Available+write.Out
+		if cache := ss.WiredTiger.Cache; cache.MaxBytesConfigured > 0 {
+			if dirty := 100 * float64(cache.TrackedDirtyBytes) / float64(cache.MaxBytesConfigured); dirty > m.CacheDirtyPercent {
+				m.CacheDirtyPercent = dirty
+			}
+		}
+		minutes[t] = m
+	}
+
+	first := map[int64]SystemMetricsDoc{}
+	last := map[int64]SystemMetricsDoc{}
+	for _, sm := range d.SystemMetricsList {
+		if sm.Start.IsZero() {
+			continue
+		}
+		t := sm.Start.Unix() - sm.Start.Unix()%60
+		if _, ok := first[t]; ok == false {
+			first[t] = sm
+		}
+		last[t] = sm
+	}
+	for t, a := range first {
+		m, ok := minutes[t]
+		b := last[t]
+		if ok == false || b.Start.After(a.Start) == false {
+			continue
+		}
+		m.CPUPercent, m.IOWaitPercent = getCPUPercents(a.CPU, b.CPU)
+		elapsed := float64(b.Start.Sub(a.Start) / time.Millisecond)
+		for name, disk := range b.Disks {
+			if pct := 100 * float64(disk.IOTimeMS-a.Disks[name].IOTimeMS) / elapsed; pct > m.DiskUtilPercent {
+				m.DiskUtilPercent = pct
+			}
+		}
+		minutes[t] = m
+	}
+	return minutes
+}
+
+// getCPUPercents returns busy and iowait percentages of CPU between two data points
+func getCPUPercents(a CPUMetrics, b CPUMetrics) (float64, float64) {
+	sum := func(c CPUMetrics) int64 {
+		return c.IdleMS + c.IOWaitMS + c.NiceMS + c.SoftirqMS + c.StealMS + c.SystemMS + c.UserMS
+	}
+	total := float64(sum(b) - sum(a))
+	if total <= 0 {
+		return 0, 0
+	}
+	return 100 * (total - float64(b.IdleMS-a.IdleMS)) / total, 100 * float64(b.IOWaitMS-a.IOWaitMS) / total
+}
+
+// getSaturations returns saturated resources of a minute
+func getSaturations(m MinuteStatsDoc) []string {
+	saturations := []string{}
+	if m.Queued >= saturatedQueued {
+		saturations = append(saturations, fmt.Sprintf("queued %d", m.Queued))
+	}
+	if m.ReadTickets > 0 && 100*m.ReadTicketsOut >= saturatedTicketsPercent*m.ReadTickets {
+		saturations = append(saturations, fmt.Sprintf("read tickets %d/%d", m.ReadTicketsOut, m.ReadTickets))
+	}
+	if m.WriteTickets > 0 && 100*m.WriteTicketsOut >= saturatedTicketsPercent*m.WriteTickets {
+		saturations = append(saturations, fmt.Sprintf("write tickets %d/%d", m.WriteTicketsOut, m.WriteTickets))
+	}
+	if m.CacheDirtyPercent >= saturatedCacheDirtyPercent {
+		saturations = append(saturations, fmt.Sprintf("cache dirty %.1f%%", m.CacheDirtyPercent))
+	}
+	if m.DiskUtilPercent >= saturatedDiskUtilPercent {
+		saturations = append(saturations, fmt.Sprintf("disk util %.1f%%", m.DiskUtilPercent))
+	}
+	if m.CPUPercent >= saturatedCPUPercent {
+		saturations = append(saturations, fmt.Sprintf("cpu %.1f%%", m.CPUPercent))
+	}
+	return saturations
+}
+
+// getCorrelationVerdict tells whether slow ops likely caused the load or were its victims, by milliseconds of
+// inefficient patterns
+func getCorrelationVerdict(m MinuteStatsDoc) string {
+	if m.SlowOps == 0 {
+		return "no slow ops logged"
+	} else if len(m.Saturations) == 0 {
+		return "slow ops spike without resource saturation, e.g. locks or application"
+	}
+	inefficient := 0
+	for _, p := range m.Patterns {
+		if p.Inefficient == true {
+			inefficient += p.TotalMilli
+		}
+	}
+	if 2*inefficient >= m.SlowMilli {
+		return "cause: inefficient slow ops likely drove the load"
+	}
+	return "victims: slow ops likely waited on saturated resources"
+}
+
+// GetLogDiagCorrelationSummary returns minutes of slow ops spikes or resource saturations
+func GetLogDiagCorrelationSummary(list []MinuteStatsDoc) string {
+	var buffer bytes.Buffer
+	if len(list) == 0 {
+		return "No slow ops spikes or resource saturations found in time range of FTDC data."
+	}
+	buffer.WriteString("Slow ops spikes and resource saturations by minute (UTC):\n")
+	for _, m := range list {
+		spike := ""
+		if m.IsSpike == true {
+			spike = " [SPIKE]"
+		}
+		buffer.WriteString(fmt.Sprintf("\n%s slow ops: %d, total: %s%s\n", m.Time.Format("2006-01-02T15:04"), m.SlowOps,
+			strings.TrimSpace(util.MilliToTimeString(float64(m.SlowMilli))), spike))
+		buffer.WriteString(fmt.Sprintf("  queued: %d, tickets r/w: %d/%d %d/%d, cache dirty: %.1f%%, disk util: %.1f%%, cpu: %.1f%% (iowait %.1f%%)\n",
+			m.Queued, m.ReadTicketsOut, m.ReadTickets, m.WriteTicketsOut, m.WriteTickets, m.CacheDirtyPercent, m.DiskUtilPercent,
+			m.CPUPercent, m.IOWaitPercent))
+		if len(m.Saturations) > 0 {
+			buffer.WriteString("  saturated: " + strings.Join(m.Saturations, ", ") + "\n")
+		}
+		for _, p := range m.Patterns {
+			flag := ""
+			if p.Inefficient == true {
+				flag = " [INEFFICIENT]"
+			}
+			buffer.WriteString(fmt.Sprintf("    %6d %8s  %s %s %s%s\n", p.Count,
+				strings.TrimSpace(util.MilliToTimeString(float64(p.TotalMilli))), p.Command, p.Namespace, p.Filter, flag))
+		}
+		buffer.WriteString("  " + m.Verdict + "\n")
+	}
+	return buffer.String()
+}
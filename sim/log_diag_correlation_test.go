@@ -0,0 +1,95 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package sim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simagix/keyhole/mdb"
+	"github.com/simagix/keyhole/sim/util"
+)
+
+func getTestCorrelationData() (*util.LogInfo, *DiagnosticData) {
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	d := NewDiagnosticData(10)
+	cpu := CPUMetrics{}
+	for i := 0; i < 4; i++ {
+		minute := start.Add(time.Duration(i) * time.Minute)
+		for _, sec := range []int{0, 30} {
+			ss := mdb.ServerStatusDoc{LocalTime: minute.Add(time.Duration(sec) * time.Second)}
+			ss.WiredTiger.Cache.MaxBytesConfigured = 1000
+			ss.WiredTiger.Cache.TrackedDirtyBytes = 50
+			ss.WiredTiger.ConcurrentTransactions.Read.Available = 120
+			ss.WiredTiger.ConcurrentTransactions.Read.Out = 8
+			ss.WiredTiger.ConcurrentTransactions.Write.Available = 128
+			if i == 1 { // dirty cache
+				ss.WiredTiger.Cache.TrackedDirtyBytes = 250
+			} else if i == 2 { // queued and CPU bound
+				ss.GlobalLock.CurrentQueue.Readers = 15
+			}
+			d.ServerStatusList = append(d.ServerStatusList, ss)
+		}
+		d.SystemMetricsList = append(d.SystemMetricsList, SystemMetricsDoc{Start: minute, CPU: cpu})
+		if i == 2 {
+			cpu.IdleMS += 500
+			cpu.UserMS += 9500
+		} else {
+			cpu.IdleMS += 9000
+			cpu.UserMS += 1000
+		}
+		d.SystemMetricsList = append(d.SystemMetricsList, SystemMetricsDoc{Start: minute.Add(50 * time.Second), CPU: cpu})
+	}
+
+	li := &util.LogInfo{}
+	scan := util.OpPerformanceDoc{Command: "find", Filter: "{ name: 1 }", Namespace: "keyhole.users", Scan: util.COLLSCAN,
+		Buckets: map[int64]util.OpBucketDoc{}}
+	ixscan := util.OpPerformanceDoc{Command: "find", Filter: "{ email: 1 }", Namespace: "keyhole.users", Index: "{ email: 1 }",
+		Buckets: map[int64]util.OpBucketDoc{}}
+	for i := 0; i < 4; i++ {
+		t := start.Unix() + int64(60*i)
+		ixscan.Buckets[t] = util.OpBucketDoc{Count: 2, TotalMilli: 400}
+		if i == 2 {
+			scan.Buckets[t] = util.OpBucketDoc{Count: 18, TotalMilli: 36000}
+		}
+	}
+	li.OpsPatterns = []util.OpPerformanceDoc{ixscan, scan}
+	return li, d
+}
+
+func TestGetLogDiagCorrelation(t *testing.T) {
+	li, d := getTestCorrelationData()
+	list := GetLogDiagCorrelation(li, d)
+	bytes, _ := json.MarshalIndent(list, "", "  ")
+	t.Log(string(bytes))
+	if len(list) != 2 {
+		t.Fatal("expected 2 minutes, but got", len(list))
+	}
+	victim, spike := list[0], list[1]
+	if victim.IsSpike == true || len(victim.Saturations) != 1 || strings.HasPrefix(victim.Saturations[0], "cache dirty") == false ||
+		strings.HasPrefix(victim.Verdict, "victims") == false {
+		t.Fatal("expected victims of dirty cache", victim.Saturations, victim.Verdict)
+	}
+	if spike.IsSpike == false || spike.SlowOps != 20 || spike.Queued != 15 || spike.CPUPercent != 95 {
+		t.Fatal("expected a spike of 20 slow ops, 15 queued and 95% CPU", spike.SlowOps, spike.Queued, spike.CPUPercent)
+	}
+	if len(spike.Patterns) != 2 || spike.Patterns[0].Inefficient == false || strings.HasPrefix(spike.Verdict, "cause") == false {
+		t.Fatal("expected inefficient patterns as cause", spike.Verdict)
+	}
+}
+
+func TestGetLogDiagCorrelationSummary(t *testing.T) {
+	li, d := getTestCorrelationData()
+	str := GetLogDiagCorrelationSummary(GetLogDiagCorrelation(li, d))
+	t.Log(str)
+	for _, s := range []string{"[SPIKE]", "[INEFFICIENT]", "saturated: queued 15, cpu 95.0%", "cause:", "victims:"} {
+		if strings.Contains(str, s) == false {
+			t.Fatal("expected", s)
+		}
+	}
+	if str = GetLogDiagCorrelationSummary([]MinuteStatsDoc{}); strings.HasPrefix(str, "No slow ops") == false {
+		t.Fatal(str)
+	}
+}
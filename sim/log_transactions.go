@@ -0,0 +1,225 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package sim
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/simagix/keyhole/mdb"
+	"github.com/simagix/keyhole/sim/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// transactions of commands of logs
+var logTxCommands = map[string]string{
+	"aggregate": "aggregate", "count": "count", "delete": "remove", "find": "find", "remove": "remove", "update": "update",
+}
+
+// GetTransactionsFromLogInfo returns transactions of query patterns of a log analysis.  Weights are derived from
+// counts of ops, values templates from literals of the slowest log lines, and indexes from plan summaries.
+func GetTransactionsFromLogInfo(li *util.LogInfo) TransactionDoc {
+	tdoc := TransactionDoc{Transactions: []Transaction{}, Indexes: []bson.M{}}
+	total := 0
+	for _, doc := range li.OpsPatterns {
+		total += doc.Count
+	}
+	indexes := map[string]bool{}
+	for _, doc := range li.OpsPatterns {
+		c, ok := logTxCommands[doc.Command]
+		if ok == false {
+			continue
+		}
+		filter, line := doc.Filter, ""
+		if len(doc.Examples) > 0 {
+			line = doc.Examples[0].Log
+			if strings.HasPrefix(line, "[") { // merged results
+				line = line[strings.Index(line, "] ")+2:]
+			}
+			if _, str, ok := util.ParseLogLine(line); ok {
+				filter = str
+			}
+		}
+		node, _, err := util.ParseShellDoc(filter)
+		if err != nil || node.IsDoc() == false {
+			continue
+		}
+		tx := Transaction{C: c, Filter: getTemplateValue(node).(bson.M)}
+		tx.Weight = int(math.Round(100 * float64(doc.Count) / float64(total)))
+		if tx.Weight < 1 {
+			tx.Weight = 1
+		}
+		if c == "update" {
+			tx.Op = getUpdateTemplate(line)
+		} else if c == "aggregate" {
+			tx.Pipe = getPipelineTemplate(line, tx.Filter)
+			tx.Filter = nil
+		}
+		tdoc.Transactions = append(tdoc.Transactions, tx)
+
+		if index := getIndexTemplate(doc); len(index) > 0 {
+			key := mdb.Stringify(index)
+			if indexes[key] == false {
+				indexes[key] = true
+				tdoc.Indexes = append(tdoc.Indexes, index)
+			}
+		}
+	}
+	sort.SliceStable(tdoc.Transactions, func(i, j int) bool { return tdoc.Transactions[i].Weight > tdoc.Transactions[j].Weight })
+	return tdoc
+}
+
+// WriteTransactions writes transactions to a file of the --tx format
+func WriteTransactions(filename string, tdoc TransactionDoc) error {
+	data, err := json.MarshalIndent(tdoc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// getTemplateValue converts a parsed value to a template of util.RandomizeDocument, query shape placeholders,
+// e.g. <number>, become default values
+func getTemplateValue(node *util.ShapeNode) interface{} {
+	if node.IsDoc() == true {
+		doc := bson.M{}
+		for i, key := range node.Keys {
+			doc[key] = getTemplateValue(node.Nodes[i])
+		}
+		return doc
+	} else if node.IsArray() == true {
+		arr := []interface{}{}
+		for _, elem := range node.Nodes {
+			arr = append(arr, getTemplateValue(elem))
+		}
+		return arr
+	}
+	typ, text := node.Type, node.Text
+	if strings.HasPrefix(text, "<") && strings.HasSuffix(text, ">") { // placeholders
+		typ, text = text[1:len(text)-1], ""
+	}
+	switch typ {
+	case "bool":
+		return text != "false"
+	case "null":
+		return nil
+	case "date", "timestamp":
+		return "$date"
+	case "objectId":
+		return "$oId"
+	case "number", "decimal":
+		if i := strings.Index(text, "("); i > 0 { // e.g. NumberLong(5)
+			text = strings.Trim(text[i+1:len(text)-1], `"'`)
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+		return 100
+	case "regex":
+		if i := strings.LastIndex(text, "/"); i > 0 {
+			return bson.M{"$regex": text[1:i], "$options": text[i+1:]}
+		}
+		return bson.M{"$regex": "regex"}
+	case "string":
+		if s, err := strconv.Unquote(text); err == nil {
+			return s
+		} else if text == "" {
+			return "string"
+		}
+	}
+	return text
+}
+
+// getUpdateTemplate returns update of a log line, a $set of lastUpdated if not found
+func getUpdateTemplate(line string) bson.M {
+	str := ""
+	if strings.HasPrefix(line, "{") {
+		var doc bson.D
+		if bson.UnmarshalExtJSON([]byte(line), false, &doc) == nil {
+			command := getElemValue(getElemValue(doc, "attr"), "command")
+			update := getElemValue(command, "u")
+			if arr, ok := getElemValue(command, "updates").(bson.A); ok && len(arr) > 0 {
+				update = getElemValue(arr[0], "u")
+			}
+			if update != nil {
+				str = util.ToShellString(update)
+			}
+		}
+	} else if strings.Index(line, ", u: {") > 0 {
+		str = util.GetDocByField(line, ", u: ")
+	} else if strings.Index(line, " update: {") > 0 {
+		str = util.GetDocByField(line, " update: ")
+	}
+	if node, _, err := util.ParseShellDoc(str); err == nil && node.IsDoc() == true {
+		return getTemplateValue(node).(bson.M)
+	}
+	return bson.M{"$set": bson.M{"lastUpdated": "$date"}}
+}
+
+// getPipelineTemplate returns stages of the pipeline of a log line, a $match of the filter if not found
+func getPipelineTemplate(line string, filter bson.M) []bson.M {
+	var node *util.ShapeNode
+	if strings.HasPrefix(line, "{") {
+		var doc bson.D
+		if bson.UnmarshalExtJSON([]byte(line), false, &doc) == nil {
+			if arr, ok := getElemValue(getElemValue(getElemValue(doc, "attr"), "command"), "pipeline").(bson.A); ok {
+				node, _, _ = util.ParseShellDoc(util.ToShellString(arr))
+			}
+		}
+	} else if cmd, _, err := util.ParseShellDoc(util.GetDocByField(line, "command: aggregate ")); err == nil && cmd.IsDoc() == true {
+		for i, key := range cmd.Keys {
+			if key == "pipeline" {
+				node = cmd.Nodes[i]
+			}
+		}
+	}
+	pipe := []bson.M{}
+	if node != nil && node.IsArray() == true {
+		for _, stage := range node.Nodes {
+			if stage.IsDoc() == true {
+				pipe = append(pipe, getTemplateValue(stage).(bson.M))
+			}
+		}
+	}
+	if len(pipe) == 0 {
+		return []bson.M{bson.M{"$match": filter}}
+	}
+	return pipe
+}
+
+func getElemValue(doc interface{}, key string) interface{} {
+	if d, ok := doc.(bson.D); ok {
+		for _, e := range d {
+			if e.Key == key {
+				return e.Value
+			}
+		}
+	}
+	return nil
+}
+
+// getIndexTemplate returns index of a plan summary, or by the ESR rule of a COLLSCAN pattern
+func getIndexTemplate(doc util.OpPerformanceDoc) bson.M {
+	index := bson.M{}
+	if doc.Scan == util.COLLSCAN {
+		esr, err := mdb.GetESRIndex(doc.Filter)
+		if err != nil {
+			return index
+		}
+		for _, field := range esr.GetFields() {
+			index[field] = 1
+			if d, ok := esr.Directions[field]; ok {
+				index[field] = d
+			}
+		}
+	} else if node, _, err := util.ParseShellDoc(doc.Index); err == nil && node.IsDoc() == true {
+		for i, key := range node.Keys {
+			index[key] = getTemplateValue(node.Nodes[i])
+		}
+	}
+	return index
+}
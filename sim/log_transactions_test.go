@@ -0,0 +1,91 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package sim
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/simagix/keyhole/sim/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var txLogLines = []string{
+	`2019-03-01T10:00:01.000+0000 I COMMAND  [conn1] command keyhole.users command: find { find: "users", filter: { email: "ken.chen@simagix.com", since: { $gte: new Date(1546300800000) } }, sort: { name: -1 }, $db: "keyhole" } planSummary: IXSCAN { email: 1 } keysExamined:1 docsExamined:1 nreturned:1 reslen:300 150ms`,
+	`2019-03-01T10:00:02.000+0000 I WRITE    [conn2] update keyhole.users command: { q: { account: 12345678 }, u: { $set: { status: "active" } }, multi: false, upsert: false } planSummary: COLLSCAN keysExamined:0 docsExamined:5000 nMatched:1 nModified:1 numYields:39 2000ms`,
+	`2019-03-01T10:00:03.000+0000 I COMMAND  [conn3] command keyhole.users command: aggregate { aggregate: "users", pipeline: [ { $match: { name: /^Ken/i } }, { $group: { _id: "$state", total: { $sum: 1 } } }, { $limit: 10 } ], cursor: {}, $db: "keyhole" } planSummary: COLLSCAN keysExamined:0 docsExamined:5000 nreturned:1 reslen:300 12000ms`,
+}
+
+func TestGetTransactionsFromLogInfo(t *testing.T) {
+	li := util.LogInfo{}
+	for i, line := range txLogLines {
+		op, filter, ok := util.ParseLogLine(line)
+		if ok == false {
+			t.Fatal("not parsed", line)
+		}
+		t.Log(op, filter)
+		doc := util.OpPerformanceDoc{Command: op, Count: 10 * (i + 1), Filter: filter, Namespace: "keyhole.users",
+			Scan: util.COLLSCAN, Examples: []util.SlowOps{{Log: line}}}
+		if i == 0 {
+			doc.Scan, doc.Index = "", "{ email: 1 }"
+		}
+		li.OpsPatterns = append(li.OpsPatterns, doc)
+	}
+	tdoc := GetTransactionsFromLogInfo(&li)
+	bytes, _ := json.MarshalIndent(tdoc, "", "  ")
+	t.Log(string(bytes))
+	if len(tdoc.Transactions) != 3 || len(tdoc.Indexes) != 3 {
+		t.Fatal(string(bytes))
+	}
+	tx := tdoc.Transactions[2] // sorted by weights
+	if tx.C != "find" || tx.Weight != 17 || tx.Filter["email"] != "ken.chen@simagix.com" {
+		t.Fatal(tx)
+	}
+	tx = tdoc.Transactions[1]
+	if tx.C != "update" || tx.Filter["account"] != float64(12345678) || util.ToShellString(tx.Op) != `{ $set: { status: "active" } }` {
+		t.Fatal(tx, util.ToShellString(tx.Op))
+	}
+	if tx = tdoc.Transactions[0]; tx.C != "aggregate" || len(tx.Pipe) != 3 || tx.Filter != nil ||
+		util.ToShellString(tx.Pipe[1]) != `{ $group: { _id: "$state", total: { $sum: 1 } } }` || tx.Pipe[2]["$limit"] != float64(10) {
+		t.Fatal(tx)
+	}
+	if match, ok := tx.Pipe[0]["$match"].(bson.M); ok == false || match["name"] == nil {
+		t.Fatal(tx.Pipe)
+	}
+}
+
+func TestGetPipelineTemplate(t *testing.T) {
+	line := `{"t":{"$date":"2020-08-10T12:00:03.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn3","msg":"Slow query","attr":{"type":"command","ns":"keyhole.cars","command":{"aggregate":"cars","pipeline":[{"$match":{"brand":{"$in":["BMW","Audi"]}}},{"$group":{"_id":"$color"}}],"$db":"keyhole"},"planSummary":"COLLSCAN","durationMillis":12000}}`
+	if pipe := getPipelineTemplate(line, bson.M{}); len(pipe) != 2 || util.ToShellString(pipe[1]) != `{ $group: { _id: "$color" } }` {
+		t.Fatal(pipe)
+	}
+	if pipe := getPipelineTemplate("", bson.M{"a": 1}); len(pipe) != 1 || pipe[0]["$match"] == nil {
+		t.Fatal(pipe)
+	}
+}
+
+func TestGetRandomPipeline(t *testing.T) {
+	pipe := []bson.M{{"$match": bson.M{"since": "$date", "brand": "BMW"}}, {"$group": bson.M{"_id": "$color"}}}
+	pipeline := getRandomPipeline(pipe)
+	if len(pipeline) != 2 || pipeline[0][0].Key != "$match" || pipeline[1][0].Key != "$group" {
+		t.Fatal(pipeline)
+	}
+	match := pipeline[0][0].Value.(map[string]interface{})
+	if _, ok := match["since"].(time.Time); ok == false {
+		t.Fatal(match)
+	}
+}
+
+func TestWriteTransactions(t *testing.T) {
+	filename := os.TempDir() + "/keyhole-tx.json"
+	defer os.Remove(filename)
+	tdoc := TransactionDoc{Transactions: []Transaction{{C: "find", Weight: 2}}}
+	if err := WriteTransactions(filename, tdoc); err != nil {
+		t.Fatal(err)
+	}
+	if doc := GetTransactions(filename); len(doc.Transactions) != 1 || doc.Transactions[0].Weight != 2 {
+		t.Fatal(doc)
+	}
+}
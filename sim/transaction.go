@@ -20,6 +20,7 @@ type Transaction struct {
 	Filter bson.M   `json:"filter"`
 	Op     bson.M   `json:"op"`
 	Pipe   []bson.M `json:"pipe"`
+	Weight int      `json:"weight,omitempty"` // executions per cycle, defaults to 1
 }
 
 // TransactionDoc -
@@ -44,50 +45,76 @@ func GetTransactions(filename string) TransactionDoc {
 }
 
 func execTXByTemplateAndTX(c *mongo.Collection, doc bson.M, transactions []Transaction) int {
+	count := 0
+	for _, tx := range transactions {
+		for n := 0; n < tx.Weight || n == 0; n++ {
+			execTXByTemplate(c, doc, tx)
+			count++
+		}
+	}
+	return count
+}
+
+func execTXByTemplate(c *mongo.Collection, doc bson.M, tx Transaction) {
 	var ctx = context.Background()
 	var op = make(map[string]interface{})
 
-	for _, tx := range transactions {
-		if tx.C == "insert" {
-			c.InsertOne(ctx, doc)
-		} else {
-			bytes, _ := json.Marshal(tx.Filter)
-			cmd := make(map[string]interface{})
-			filter := make(map[string]interface{})
-			json.Unmarshal(bytes, &cmd)
-			util.RandomizeDocument(&filter, cmd, false)
+	if tx.C == "insert" {
+		c.InsertOne(ctx, doc)
+	} else {
+		filter := getRandomFilter(tx.Filter)
 
-			if tx.C == "find" {
-				c.Find(ctx, filter)
-			} else if tx.C == "findOne" {
-				c.FindOne(ctx, filter)
-			} else if tx.C == "update" {
-				bytes, _ = json.Marshal(tx.Op)
-				json.Unmarshal(bytes, &op)
-				util.RandomizeDocument(&filter, op, false)
-				c.UpdateMany(ctx, filter, op)
-			} else if tx.C == "updateAll" || tx.C == "updateMany" {
-				bytes, _ = json.Marshal(tx.Op)
-				json.Unmarshal(bytes, &op)
-				util.RandomizeDocument(&filter, op, false)
-				c.UpdateMany(ctx, filter, op)
-			} else if tx.C == "remove" || tx.C == "deleteOne" {
-				c.DeleteOne(ctx, filter)
-			} else if tx.C == "removeAll" || tx.C == "deleteMany" {
-				c.DeleteMany(ctx, filter)
-			} else if tx.C == "aggregate" {
-				// example
-				// pipeline := mongo.Pipeline{
-				// 	{{"$group", bson.D{{"_id", "$state"}, {"totalPop", bson.D{{"$sum", "$pop"}}}}}},
-				// 	{{"$match", bson.D{{"totalPop", bson.D{{"$gte", 10 * 1000 * 1000}}}}}},
-				// }
-				b, _ := json.Marshal(tx.Pipe)
-				c.Aggregate(ctx, mdb.MongoPipeline(string(b)))
-			}
+		if tx.C == "find" {
+			c.Find(ctx, filter)
+		} else if tx.C == "findOne" {
+			c.FindOne(ctx, filter)
+		} else if tx.C == "count" {
+			c.CountDocuments(ctx, filter)
+		} else if tx.C == "update" {
+			bytes, _ := json.Marshal(tx.Op)
+			json.Unmarshal(bytes, &op)
+			util.RandomizeDocument(&filter, op, false)
+			c.UpdateMany(ctx, filter, op)
+		} else if tx.C == "updateAll" || tx.C == "updateMany" {
+			bytes, _ := json.Marshal(tx.Op)
+			json.Unmarshal(bytes, &op)
+			util.RandomizeDocument(&filter, op, false)
+			c.UpdateMany(ctx, filter, op)
+		} else if tx.C == "remove" || tx.C == "deleteOne" {
+			c.DeleteOne(ctx, filter)
+		} else if tx.C == "removeAll" || tx.C == "deleteMany" {
+			c.DeleteMany(ctx, filter)
+		} else if tx.C == "aggregate" {
+			// example
+			// pipeline := mongo.Pipeline{
+			// 	{{"$group", bson.D{{"_id", "$state"}, {"totalPop", bson.D{{"$sum", "$pop"}}}}}},
+			// 	{{"$match", bson.D{{"totalPop", bson.D{{"$gte", 10 * 1000 * 1000}}}}}},
+			// }
+			c.Aggregate(ctx, getRandomPipeline(tx.Pipe))
 		}
 	}
+}
+
+// getRandomFilter returns a filter of random values of a template
+func getRandomFilter(template interface{}) map[string]interface{} {
+	bytes, _ := json.Marshal(template)
+	cmd := make(map[string]interface{})
+	filter := make(map[string]interface{})
+	json.Unmarshal(bytes, &cmd)
+	util.RandomizeDocument(&filter, cmd, false)
+	return filter
+}
 
-	return len(transactions)
+// getRandomPipeline returns a pipeline of a template, values of $match stages are randomized as filters are
+func getRandomPipeline(pipe []bson.M) mongo.Pipeline {
+	b, _ := json.Marshal(pipe)
+	pipeline := mdb.MongoPipeline(string(b))
+	for i, stage := range pipe {
+		if match, ok := stage["$match"]; ok && i < len(pipeline) {
+			pipeline[i] = bson.D{{Key: "$match", Value: getRandomFilter(match)}}
+		}
+	}
+	return pipeline
 }
 
 func execTx(c *mongo.Collection, doc bson.M) (bson.M, error) {
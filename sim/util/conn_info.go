@@ -0,0 +1,422 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConnInfo keeps connections, client metadata and authentications from logs
+type ConnInfo struct {
+	AppNames map[string]int          // by application name
+	Auths    map[string]AuthDoc      // by user@db and mechanism, if any
+	Buckets  map[int64]ConnBucketDoc // by epoch seconds of beginnings of intervals
+	Drivers  map[string]int          // by driver name and version
+	IPs      map[string]ConnIPDoc    // by source IP
+	PeakOpen int                     // peak open connections
+	PeakTime time.Time               // time of peak open connections
+	filename string
+	interval int
+	silent   bool
+}
+
+// AuthDoc counts authentications of a user and a mechanism
+type AuthDoc struct {
+	Failures  int
+	Mechanism string
+	Successes int
+	User      string
+}
+
+// ConnBucketDoc counts connections of a time interval
+type ConnBucketDoc struct {
+	Accepted int
+	Ended    int
+	MaxOpen  int
+	IPs      map[string]int // accepted by source IP
+}
+
+// ConnIPDoc counts connections of a source IP
+type ConnIPDoc struct {
+	Accepted int
+	Ended    int
+}
+
+// connEvent is a connection, metadata or authentication event of a log line
+type connEvent struct {
+	appName   string
+	driver    string
+	event     string // accepted, ended, metadata, auth_ok, auth_failed
+	ip        string
+	mechanism string
+	open      int
+	timestamp time.Time
+	user      string
+}
+
+var connAcceptedMatched = regexp.MustCompile(`connection accepted from (\S+):\d+ #\d+ \((\d+) connections? now open\)`)
+var connEndedMatched = regexp.MustCompile(`end connection (\S+):\d+ \((\d+) connections? now open\)`)
+var connMetadataMatched = regexp.MustCompile(`received client metadata from (\S+):\d+ conn\d*: (.*)$`)
+var authSuccessMatched = regexp.MustCompile(`Successfully authenticated as principal (\S+) on (\S+) from client (\S+):\d+`)
+var authFailureMatched = regexp.MustCompile(`(?:SASL )?(\S+) authentication failed for (\S+) on (\S+) from client (\S+):\d+`)
+
+// NewConnInfo -
+func NewConnInfo(filename string) *ConnInfo {
+	return &ConnInfo{filename: filename, interval: 60, AppNames: map[string]int{}, Auths: map[string]AuthDoc{},
+		Buckets: map[int64]ConnBucketDoc{}, Drivers: map[string]int{}, IPs: map[string]ConnIPDoc{}}
+}
+
+// SetInterval sets seconds of time buckets
+func (ci *ConnInfo) SetInterval(interval int) {
+	if interval > 0 {
+		ci.interval = interval
+	}
+}
+
+// SetSilent -
+func (ci *ConnInfo) SetSilent(silent bool) {
+	ci.silent = silent
+}
+
+// Analyze parses a log file and returns summaries
+func (ci *ConnInfo) Analyze() (string, error) {
+	if err := ci.Parse(); err != nil {
+		return "", err
+	}
+	return ci.GetSummary(), nil
+}
+
+// Parse reads connection, client metadata and authentication events of a log file, or of rotated logs of a directory
+// or a glob in order of their first timestamps
+func (ci *ConnInfo) Parse() error {
+	var err error
+	var filenames []string
+	var totalSize, doneSize int64
+	if filenames, err = GetLogFilenames(ci.filename); err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		fileInfo, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		totalSize += fileInfo.Size()
+	}
+	for _, filename := range filenames {
+		var size int64
+		if size, err = ci.parseFile(filename, doneSize, totalSize); err != nil {
+			return err
+		}
+		doneSize += size
+	}
+	if ci.silent == false {
+		fmt.Fprintf(os.Stderr, "\r     \r")
+	}
+	return nil
+}
+
+// parseFile reads events of a log file and returns bytes read, progress is of total bytes of all files
+func (ci *ConnInfo) parseFile(filename string, doneSize int64, totalSize int64) (int64, error) {
+	var err error
+	var file *os.File
+	var reader *bufio.Reader
+	var counter *ByteCounter
+	if file, err = os.Open(filename); err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if reader, counter, err = NewCountingReader(file); err != nil {
+		return 0, err
+	}
+	for index := 1; ; index++ {
+		var buf []byte
+		var isPrefix bool
+		buf, isPrefix, err = reader.ReadLine() // 0x0A separator = newline
+		str := string(buf)
+		for isPrefix == true {
+			var bbuf []byte
+			bbuf, isPrefix, err = reader.ReadLine()
+			str += string(bbuf)
+		}
+		if err != nil {
+			break
+		}
+		if ev, ok := parseConnEvent(str); ok {
+			ci.add(ev)
+		}
+		if index%logBatchSize == 0 && ci.silent == false && totalSize > 0 {
+			fmt.Fprintf(os.Stderr, "\r%3d%% ", 100*(doneSize+counter.Count())/totalSize)
+		}
+	}
+	if err != io.EOF {
+		return counter.Count(), err
+	}
+	return counter.Count(), nil
+}
+
+func (ci *ConnInfo) add(ev connEvent) {
+	var bucket ConnBucketDoc
+	var t int64
+	if ev.timestamp.IsZero() == false {
+		t = ev.timestamp.Unix() - ev.timestamp.Unix()%int64(ci.interval)
+		bucket = ci.Buckets[t]
+	}
+	switch ev.event {
+	case "accepted", "ended":
+		ipDoc := ci.IPs[ev.ip]
+		if ev.event == "accepted" {
+			ipDoc.Accepted++
+			bucket.Accepted++
+			if bucket.IPs == nil {
+				bucket.IPs = map[string]int{}
+			}
+			bucket.IPs[ev.ip]++
+		} else {
+			ipDoc.Ended++
+			bucket.Ended++
+		}
+		ci.IPs[ev.ip] = ipDoc
+		if ev.open > bucket.MaxOpen {
+			bucket.MaxOpen = ev.open
+		}
+		if ev.open > ci.PeakOpen {
+			ci.PeakOpen = ev.open
+			ci.PeakTime = ev.timestamp
+		}
+		if ev.timestamp.IsZero() == false {
+			ci.Buckets[t] = bucket
+		}
+	case "metadata":
+		if ev.driver != "" {
+			ci.Drivers[ev.driver]++
+		}
+		if ev.appName != "" {
+			ci.AppNames[ev.appName]++
+		}
+	case "auth_ok", "auth_failed":
+		key := ev.user // legacy logs of successes have no mechanisms
+		if ev.mechanism != "" {
+			key += " " + ev.mechanism
+		}
+		doc := ci.Auths[key]
+		doc.User, doc.Mechanism = ev.user, ev.mechanism
+		if ev.event == "auth_ok" {
+			doc.Successes++
+		} else {
+			doc.Failures++
+		}
+		ci.Auths[key] = doc
+	}
+}
+
+// parseConnEvent parses a legacy or a structured log line
+func parseConnEvent(str string) (connEvent, bool) {
+	if strings.HasPrefix(str, "{") {
+		return parseJSONConnEvent(str)
+	}
+	var ev connEvent
+	if i := strings.Index(str, " "); i > 0 {
+		ev.timestamp, _ = time.Parse(legacyTimeLayout, str[:i])
+	}
+	if strings.Index(str, "connection accepted from") > 0 {
+		if res := connAcceptedMatched.FindStringSubmatch(str); len(res) > 0 {
+			ev.event, ev.ip = "accepted", res[1]
+			fmt.Sscanf(res[2], "%d", &ev.open)
+			return ev, true
+		}
+	} else if strings.Index(str, "end connection") > 0 {
+		if res := connEndedMatched.FindStringSubmatch(str); len(res) > 0 {
+			ev.event, ev.ip = "ended", res[1]
+			fmt.Sscanf(res[2], "%d", &ev.open)
+			return ev, true
+		}
+	} else if strings.Index(str, "received client metadata") > 0 {
+		if res := connMetadataMatched.FindStringSubmatch(str); len(res) > 0 {
+			ev.event, ev.ip = "metadata", res[1]
+			doc := res[2]
+			driver := GetDocByField(doc, "driver: ")
+			name, version := getShellStringValue(driver, "name: "), getShellStringValue(driver, "version: ")
+			ev.driver = strings.TrimSpace(name + " " + version)
+			ev.appName = getShellStringValue(GetDocByField(doc, "application: "), "name: ")
+			return ev, true
+		}
+	} else if strings.Index(str, "Successfully authenticated") > 0 {
+		if res := authSuccessMatched.FindStringSubmatch(str); len(res) > 0 {
+			ev.event, ev.user, ev.ip = "auth_ok", res[1]+"@"+res[2], res[3]
+			return ev, true
+		}
+	} else if strings.Index(str, "authentication failed") > 0 {
+		if res := authFailureMatched.FindStringSubmatch(str); len(res) > 0 {
+			ev.event, ev.mechanism, ev.user, ev.ip = "auth_failed", res[1], res[2]+"@"+res[3], res[4]
+			return ev, true
+		}
+	}
+	return ev, false
+}
+
+// getShellStringValue returns a string value of a field, e.g. name: "mongo-java-driver"
+func getShellStringValue(str string, field string) string {
+	i := strings.Index(str, field+"\"")
+	if i < 0 {
+		return ""
+	}
+	str = str[i+len(field)+1:]
+	if i = strings.Index(str, "\""); i < 0 {
+		return ""
+	}
+	return str[:i]
+}
+
+func parseJSONConnEvent(str string) (connEvent, bool) {
+	var ev connEvent
+	var doc struct {
+		T struct {
+			Date string `json:"$date"`
+		} `json:"t"`
+		Msg  string `json:"msg"`
+		Attr struct {
+			AuthenticationDatabase string `json:"authenticationDatabase"`
+			ConnectionCount        int    `json:"connectionCount"`
+			DB                     string `json:"db"`
+			Doc                    struct {
+				Application struct {
+					Name string `json:"name"`
+				} `json:"application"`
+				Driver struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+				} `json:"driver"`
+			} `json:"doc"`
+			Mechanism     string `json:"mechanism"`
+			PrincipalName string `json:"principalName"`
+			Remote        string `json:"remote"`
+			User          string `json:"user"`
+		} `json:"attr"`
+	}
+	switch {
+	case strings.Index(str, `"Connection accepted"`) > 0, strings.Index(str, `"Connection ended"`) > 0,
+		strings.Index(str, `"client metadata"`) > 0, strings.Index(str, `"Successfully authenticated"`) > 0,
+		strings.Index(str, `"Authentication failed"`) > 0:
+	default:
+		return ev, false
+	}
+	if json.Unmarshal([]byte(str), &doc) != nil {
+		return ev, false
+	}
+	attr := doc.Attr
+	ev.timestamp, _ = time.Parse(time.RFC3339, doc.T.Date)
+	if i := strings.LastIndex(attr.Remote, ":"); i > 0 {
+		ev.ip = attr.Remote[:i]
+	}
+	ev.open = attr.ConnectionCount
+	user := attr.PrincipalName
+	if user == "" {
+		user = attr.User
+	}
+	db := attr.AuthenticationDatabase
+	if db == "" {
+		db = attr.DB
+	}
+	switch doc.Msg {
+	case "Connection accepted":
+		ev.event = "accepted"
+	case "Connection ended":
+		ev.event = "ended"
+	case "client metadata":
+		ev.event = "metadata"
+		ev.driver = strings.TrimSpace(attr.Doc.Driver.Name + " " + attr.Doc.Driver.Version)
+		ev.appName = attr.Doc.Application.Name
+	case "Successfully authenticated":
+		ev.event, ev.user, ev.mechanism = "auth_ok", user+"@"+db, attr.Mechanism
+	case "Authentication failed":
+		ev.event, ev.user, ev.mechanism = "auth_failed", user+"@"+db, attr.Mechanism
+	default:
+		return ev, false
+	}
+	return ev, true
+}
+
+type countDoc struct {
+	name  string
+	count int
+}
+
+func sortCounts(counts map[string]int) []countDoc {
+	docs := []countDoc{}
+	for k, v := range counts {
+		docs = append(docs, countDoc{k, v})
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].count == docs[j].count {
+			return docs[i].name < docs[j].name
+		}
+		return docs[i].count > docs[j].count
+	})
+	return docs
+}
+
+// GetSummary returns connections, drivers, applications and authentications summaries
+func (ci *ConnInfo) GetSummary() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("Peak open connections: %d", ci.PeakOpen))
+	if ci.PeakTime.IsZero() == false {
+		buffer.WriteString(" at " + ci.PeakTime.UTC().Format(time.RFC3339))
+	}
+	buffer.WriteString("\n\nConnections by source IP:\n")
+	buffer.WriteString(fmt.Sprintf("%-40s %10s %10s\n", "Source IP", "Accepted", "Ended"))
+	ips := map[string]int{}
+	for ip, doc := range ci.IPs {
+		ips[ip] = doc.Accepted
+	}
+	for _, doc := range sortCounts(ips) {
+		buffer.WriteString(fmt.Sprintf("%-40s %10d %10d\n", doc.name, ci.IPs[doc.name].Accepted, ci.IPs[doc.name].Ended))
+	}
+
+	buffer.WriteString(fmt.Sprintf("\nConnections by %d-second interval:\n", ci.interval))
+	buffer.WriteString(fmt.Sprintf("%-20s %10s %10s %10s  %s\n", "Time (UTC)", "Accepted", "Ended", "Max Open", "Top Source IP"))
+	times := []int64{}
+	for t := range ci.Buckets {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	for _, t := range times {
+		bucket := ci.Buckets[t]
+		top := ""
+		if list := sortCounts(bucket.IPs); len(list) > 0 {
+			top = fmt.Sprintf("%s (%d)", list[0].name, list[0].count)
+		}
+		buffer.WriteString(fmt.Sprintf("%-20s %10d %10d %10d  %s\n", time.Unix(t, 0).UTC().Format("2006-01-02T15:04:05"),
+			bucket.Accepted, bucket.Ended, bucket.MaxOpen, top))
+	}
+
+	buffer.WriteString("\nClient drivers:\n")
+	for _, doc := range sortCounts(ci.Drivers) {
+		buffer.WriteString(fmt.Sprintf("%10d  %s\n", doc.count, doc.name))
+	}
+	buffer.WriteString("\nApplications:\n")
+	for _, doc := range sortCounts(ci.AppNames) {
+		buffer.WriteString(fmt.Sprintf("%10d  %s\n", doc.count, doc.name))
+	}
+
+	buffer.WriteString("\nAuthentications:\n")
+	buffer.WriteString(fmt.Sprintf("%-40s %-16s %10s %10s\n", "User", "Mechanism", "Successes", "Failures"))
+	keys := []string{}
+	for k := range ci.Auths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc := ci.Auths[k]
+		buffer.WriteString(fmt.Sprintf("%-40s %-16s %10d %10d\n", doc.User, doc.Mechanism, doc.Successes, doc.Failures))
+	}
+	return buffer.String()
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var connLogLines = []string{
+	`2019-03-01T10:00:01.000+0000 I NETWORK  [listener] connection accepted from 10.0.0.1:52312 #1 (1 connection now open)`,
+	`2019-03-01T10:00:01.100+0000 I NETWORK  [conn1] received client metadata from 10.0.0.1:52312 conn1: { driver: { name: "mongo-java-driver", version: "3.8.2" }, os: { type: "Linux" }, platform: "Java", application: { name: "orders" } }`,
+	`2019-03-01T10:00:01.200+0000 I ACCESS   [conn1] Successfully authenticated as principal app on admin from client 10.0.0.1:52312`,
+	`2019-03-01T10:00:02.000+0000 I NETWORK  [listener] connection accepted from 10.0.0.2:52313 #2 (2 connections now open)`,
+	`2019-03-01T10:00:02.200+0000 I ACCESS   [conn2] SASL SCRAM-SHA-1 authentication failed for app on admin from client 10.0.0.2:52313 ; AuthenticationFailed: SCRAM authentication failed, storedKey mismatch`,
+	`2019-03-01T10:01:00.000+0000 I NETWORK  [conn2] end connection 10.0.0.2:52313 (1 connection now open)`,
+	`{"t":{"$date":"2019-03-01T10:01:30.000+00:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"10.0.0.1:52320","connectionId":3,"connectionCount":2}}`,
+	`{"t":{"$date":"2019-03-01T10:01:30.100+00:00"},"s":"I","c":"NETWORK","id":51800,"ctx":"conn3","msg":"client metadata","attr":{"remote":"10.0.0.1:52320","client":"conn3","doc":{"driver":{"name":"mongo-java-driver","version":"3.8.2"},"application":{"name":"orders"}}}}`,
+	`{"t":{"$date":"2019-03-01T10:01:30.200+00:00"},"s":"I","c":"ACCESS","id":20250,"ctx":"conn3","msg":"Successfully authenticated","attr":{"mechanism":"SCRAM-SHA-256","principalName":"app","authenticationDatabase":"admin","remote":"10.0.0.1:52320"}}`,
+}
+
+func TestConnInfo(t *testing.T) {
+	filename := "conn_info_test.log"
+	ioutil.WriteFile(filename, []byte(strings.Join(connLogLines, "\n")+"\n"), 0644)
+	defer os.Remove(filename)
+	ci := NewConnInfo(filename)
+	ci.SetSilent(true)
+	str, err := ci.Analyze()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci.PeakOpen != 2 || ci.IPs["10.0.0.1"].Accepted != 2 || ci.IPs["10.0.0.2"].Ended != 1 || len(ci.Buckets) != 2 {
+		t.Fatal(ci.PeakOpen, ci.IPs, ci.Buckets)
+	}
+	if ci.Drivers["mongo-java-driver 3.8.2"] != 2 || ci.AppNames["orders"] != 2 {
+		t.Fatal(ci.Drivers, ci.AppNames)
+	}
+	if ci.Auths["app@admin"].Successes != 1 || ci.Auths["app@admin SCRAM-SHA-1"].Failures != 1 || ci.Auths["app@admin SCRAM-SHA-256"].Successes != 1 {
+		t.Fatal(ci.Auths)
+	}
+	if strings.Index(str, "Peak open connections: 2 at 2019-03-01T10:00:02Z") < 0 {
+		t.Fatal(str)
+	}
+}
+
+func TestConnInfoRotatedLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyhole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "mongod.log.1"), []byte(strings.Join(connLogLines[:6], "\n")+"\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "mongod.log"), []byte(strings.Join(connLogLines[6:], "\n")+"\n"), 0644)
+	for _, pattern := range []string{dir, filepath.Join(dir, "mongod.log*")} {
+		ci := NewConnInfo(pattern)
+		ci.SetSilent(true)
+		if err = ci.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		if ci.PeakOpen != 2 || ci.IPs["10.0.0.1"].Accepted != 2 || ci.AppNames["orders"] != 2 {
+			t.Fatal(pattern, ci.PeakOpen, ci.IPs, ci.AppNames)
+		}
+	}
+}
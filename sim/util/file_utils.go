@@ -8,6 +8,7 @@ import (
 	"compress/gzip"
 	"io"
 	"os"
+	"sync/atomic"
 )
 
 // NewReader returns a reader from either a gzip or plain file
@@ -35,6 +36,46 @@ func NewReader(file *os.File) (*bufio.Reader, error) {
 	return reader, nil
 }
 
+// ByteCounter counts bytes read from a reader
+type ByteCounter struct {
+	reader io.Reader
+	count  int64
+}
+
+// Read -
+func (bc *ByteCounter) Read(p []byte) (int, error) {
+	n, err := bc.reader.Read(p)
+	atomic.AddInt64(&bc.count, int64(n))
+	return n, err
+}
+
+// Count returns number of bytes read
+func (bc *ByteCounter) Count() int64 {
+	return atomic.LoadInt64(&bc.count)
+}
+
+// NewCountingReader returns a reader from either a gzip or plain file and a counter of bytes read from the file
+func NewCountingReader(file *os.File) (*bufio.Reader, *ByteCounter, error) {
+	var buf []byte
+	var err error
+	counter := &ByteCounter{reader: file}
+	reader := bufio.NewReader(counter)
+	if buf, err = reader.Peek(2); err != nil {
+		if err == io.EOF {
+			err = nil
+		}
+		return reader, counter, err
+	}
+	if buf[0] == 31 && buf[1] == 139 {
+		var zreader *gzip.Reader
+		if zreader, err = gzip.NewReader(reader); err != nil {
+			return reader, counter, err
+		}
+		reader = bufio.NewReader(zreader)
+	}
+	return reader, counter, nil
+}
+
 // CountLines count number of '\n'
 func CountLines(reader *bufio.Reader) (int, error) {
 	buf := make([]byte, 32*1024)
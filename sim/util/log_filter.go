@@ -0,0 +1,169 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogFilter filters slow ops before aggregation
+type LogFilter struct {
+	Commands          []string
+	ExcludeNamespaces []*regexp.Regexp
+	From              time.Time
+	MinMilli          int
+	Namespaces        []*regexp.Regexp
+	Plan              string // COLLSCAN, IXSCAN or keys of an index, e.g. { a: 1 }
+	To                time.Time
+}
+
+// NewLogFilter -
+func NewLogFilter() *LogFilter {
+	return &LogFilter{}
+}
+
+// ParseLogTime parses RFC3339, yyyy-mm-ddThh:mm:ss (UTC) or yyyy-mm-dd (UTC)
+func ParseLogTime(str string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid time " + str + ", e.g. 2019-03-01T09:30:00Z")
+}
+
+// SetTimeRange sets from and to, either can be empty
+func (f *LogFilter) SetTimeRange(from string, to string) error {
+	var err error
+	if from != "" {
+		if f.From, err = ParseLogTime(from); err != nil {
+			return err
+		}
+	}
+	if to != "" {
+		if f.To, err = ParseLogTime(to); err != nil {
+			return err
+		}
+	}
+	if f.From.IsZero() == false && f.To.IsZero() == false && f.To.Before(f.From) {
+		return errors.New("to is before from")
+	}
+	return nil
+}
+
+// SetNamespaces sets comma separated regular expressions of namespaces to include and to exclude
+func (f *LogFilter) SetNamespaces(include string, exclude string) error {
+	var err error
+	if f.Namespaces, err = compileRegexps(include); err != nil {
+		return err
+	}
+	f.ExcludeNamespaces, err = compileRegexps(exclude)
+	return err
+}
+
+func compileRegexps(str string) ([]*regexp.Regexp, error) {
+	var list []*regexp.Regexp
+	for _, s := range strings.Split(str, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, re)
+	}
+	return list, nil
+}
+
+// SetCommands sets comma separated commands, e.g. find,aggregate
+func (f *LogFilter) SetCommands(commands string) {
+	f.Commands = nil
+	for _, s := range strings.Split(commands, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			f.Commands = append(f.Commands, s)
+		}
+	}
+}
+
+// SetMinMilli -
+func (f *LogFilter) SetMinMilli(milli int) {
+	f.MinMilli = milli
+}
+
+// SetPlan sets plan summary, COLLSCAN, IXSCAN or an index, e.g. { a: 1 }
+func (f *LogFilter) SetPlan(plan string) {
+	f.Plan = strings.TrimSpace(plan)
+}
+
+// IsEmpty returns true if nothing is filtered
+func (f *LogFilter) IsEmpty() bool {
+	return f.From.IsZero() && f.To.IsZero() && len(f.Namespaces) == 0 && len(f.ExcludeNamespaces) == 0 &&
+		len(f.Commands) == 0 && f.MinMilli == 0 && f.Plan == ""
+}
+
+// isMatched returns true if a slow op passes all filters; ops without timestamps pass time range
+func (f *LogFilter) isMatched(rec logRecord) bool {
+	if f.isTimeMatched(rec.timestamp, rec.milli) == false {
+		return false
+	}
+	if len(f.Commands) > 0 {
+		isFound := false
+		for _, c := range f.Commands {
+			if strings.EqualFold(c, rec.op) {
+				isFound = true
+				break
+			}
+		}
+		if isFound == false {
+			return false
+		}
+	}
+	if len(f.Namespaces) > 0 {
+		isFound := false
+		for _, re := range f.Namespaces {
+			if re.MatchString(rec.ns) {
+				isFound = true
+				break
+			}
+		}
+		if isFound == false {
+			return false
+		}
+	}
+	for _, re := range f.ExcludeNamespaces {
+		if re.MatchString(rec.ns) {
+			return false
+		}
+	}
+	return f.isPlanMatched(rec)
+}
+
+// isTimeMatched returns true if an op is in the time range and not faster than minimum milliseconds
+func (f *LogFilter) isTimeMatched(tm time.Time, milli int) bool {
+	if milli < f.MinMilli {
+		return false
+	}
+	if tm.IsZero() == false {
+		if (f.From.IsZero() == false && tm.Before(f.From)) || (f.To.IsZero() == false && tm.After(f.To)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *LogFilter) isPlanMatched(rec logRecord) bool {
+	switch f.Plan {
+	case "":
+		return true
+	case COLLSCAN:
+		return rec.scan == COLLSCAN
+	case "IXSCAN":
+		return strings.HasPrefix(rec.index, "{")
+	}
+	removeSpaces := func(s string) string { return strings.Replace(s, " ", "", -1) }
+	return removeSpaces(rec.index) == removeSpaces(f.Plan)
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogFilter(t *testing.T) {
+	tm := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	rec := logRecord{op: "find", ns: "keyhole.cars", milli: 200, index: "{ color: 1, year: 1 }", timestamp: tm}
+	f := NewLogFilter()
+	if f.IsEmpty() == false || f.isMatched(rec) == false {
+		t.Fatal("empty filter")
+	}
+	if err := f.SetTimeRange("2019-03-01T09:30:00Z", "2019-03-01"); err == nil {
+		t.Fatal("expected error of to before from")
+	}
+	f = NewLogFilter()
+	f.SetTimeRange("2019-03-01T09:30:00Z", "2019-03-01T10:30:00")
+	f.SetNamespaces(`^keyhole\.`, `\.system\.`)
+	f.SetCommands("aggregate, find")
+	f.SetMinMilli(100)
+	f.SetPlan("{color: 1, year: 1}")
+	if f.isMatched(rec) == false {
+		t.Fatal("should match", rec)
+	}
+	for _, r := range []logRecord{
+		logRecord{op: "find", ns: "keyhole.cars", milli: 200, index: "{ color: 1, year: 1 }", timestamp: tm.Add(time.Hour)},
+		logRecord{op: "find", ns: "test.cars", milli: 200, index: "{ color: 1, year: 1 }", timestamp: tm},
+		logRecord{op: "find", ns: "keyhole.system.js", milli: 200, index: "{ color: 1, year: 1 }", timestamp: tm},
+		logRecord{op: "update", ns: "keyhole.cars", milli: 200, index: "{ color: 1, year: 1 }", timestamp: tm},
+		logRecord{op: "find", ns: "keyhole.cars", milli: 50, index: "{ color: 1, year: 1 }", timestamp: tm},
+		logRecord{op: "find", ns: "keyhole.cars", milli: 200, index: "{ color: 1 }", timestamp: tm},
+	} {
+		if f.isMatched(r) == true {
+			t.Fatal("should not match", r)
+		}
+	}
+	f.SetPlan(COLLSCAN)
+	if f.isMatched(rec) == true || f.isMatched(logRecord{op: "find", ns: "keyhole.cars", milli: 200, scan: COLLSCAN}) == false {
+		t.Fatal("COLLSCAN")
+	}
+	f = NewLogFilter()
+	f.SetTimeRange("2019-03-01T09:30:00Z", "2019-03-01T10:30:00Z")
+	str := `2019-03-01T10:00:01.000Z I COMMAND  [conn1] command keyhole.cars command: find { find: "cars", filter: { color: "Red" } } planSummary: IXSCAN { color: 1 } keysExamined:1 docsExamined:1 nreturned:1 reslen:300 200ms`
+	if rec, ok := parseLegacyLogLine(str); ok == false || rec.timestamp.Equal(tm.Add(time.Second)) == false || f.isMatched(rec) == false {
+		t.Fatal("should match a timestamp with Z", rec)
+	}
+	if err := f.SetNamespaces("keyhole.(", ""); err == nil {
+		t.Fatal("expected regex error")
+	}
+}
@@ -0,0 +1,500 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LogRedactor rewrites logs with literal values replaced by deterministic placeholders or hashes
+type LogRedactor struct {
+	filename string
+	silent   bool
+}
+
+// keys of documents whose values are kept as they are
+var redactKeptKeys = map[string]bool{
+	"$db": true, "$sort": true, "batchSize": true, "collection": true, "cursor": true, "hint": true, "limit": true,
+	"lsid": true, "ns": true, "projection": true, "readConcern": true, "skip": true, "sort": true, "writeConcern": true,
+	"$clusterTime": true, "$readPreference": true, "txnNumber": true, "autocommit": true, "startTransaction": true,
+}
+
+// commands whose values, names of collections, are kept
+var redactCommandKeys = map[string]bool{
+	"aggregate": true, "count": true, "delete": true, "distinct": true, "find": true, "findAndModify": true,
+	"findandmodify": true, "getMore": true, "insert": true, "mapReduce": true, "update": true,
+}
+
+// documents of structured logs (4.4+) attributes kept as they are
+var redactKeptAttrs = map[string]bool{
+	"buildInfo": true, "flowControl": true, "locks": true, "readConcern": true, "storage": true, "writeConcern": true,
+}
+
+// attributes of structured logs (4.4+) of host names
+var redactHostAttrs = map[string]bool{
+	"host": true, "hostAndPort": true, "remote": true, "syncSource": true, "newSyncSource": true, "target": true,
+}
+
+var redactEmailMatched = regexp.MustCompile(`[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9-.]+`)
+var redactURIMatched = regexp.MustCompile(`mongodb(\+srv)?://[^\s"',]+`)
+var redactIPMatched = regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)
+var redactHostPortMatched = regexp.MustCompile(`\b[a-zA-Z][\w-]*(\.[\w-]+)+:\d+\b`)
+var redactHostKeyMatched = regexp.MustCompile(`\bhost=\S+`)
+var redactKeptDocMatched = regexp.MustCompile(`(locks:|storage:|flowControl:|[A-Z_]+ )$`)
+
+// NewLogRedactor returns log redactor constructor
+func NewLogRedactor(filename string) *LogRedactor {
+	return &LogRedactor{filename: filename}
+}
+
+// SetSilent -
+func (lr *LogRedactor) SetSilent(silent bool) {
+	lr.silent = silent
+}
+
+// Redact writes a redacted log, both legacy and structured formats, to a file
+func (lr *LogRedactor) Redact(outfile string) error {
+	var err error
+	var file, ofile *os.File
+	var fileInfo os.FileInfo
+	var reader *bufio.Reader
+	var counter *ByteCounter
+	if file, err = os.Open(lr.filename); err != nil {
+		return err
+	}
+	defer file.Close()
+	if fileInfo, err = file.Stat(); err != nil {
+		return err
+	}
+	if reader, counter, err = NewCountingReader(file); err != nil {
+		return err
+	}
+	if ofile, err = os.Create(outfile); err != nil {
+		return err
+	}
+	defer ofile.Close()
+	writer := bufio.NewWriter(ofile)
+	for index := 0; ; index++ {
+		var buf []byte
+		var isPrefix bool
+		buf, isPrefix, err = reader.ReadLine()
+		str := string(buf)
+		for isPrefix == true {
+			var bbuf []byte
+			bbuf, isPrefix, err = reader.ReadLine()
+			str += string(bbuf)
+		}
+		if err != nil {
+			break
+		}
+		writer.WriteString(RedactLogLine(str) + "\n")
+		if lr.silent == false && index%logBatchSize == 0 && fileInfo.Size() > 0 {
+			fmt.Fprintf(os.Stderr, "\r%3d%% ", 100*counter.Count()/fileInfo.Size())
+		}
+	}
+	if lr.silent == false {
+		fmt.Fprintf(os.Stderr, "\r     \r")
+	}
+	if err != io.EOF {
+		return err
+	}
+	return writer.Flush()
+}
+
+// RedactLogLine replaces literal values of filters, documents and connection strings of a log line
+func RedactLogLine(str string) string {
+	if strings.HasPrefix(str, "{") {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err == nil {
+			for i, e := range doc {
+				if e.Key == "attr" {
+					doc[i].Value = redactAttr(e.Value)
+				} else if s, ok := e.Value.(string); ok {
+					doc[i].Value = redactText(s)
+				}
+			}
+			if data, err := bson.MarshalExtJSON(doc, false, false); err == nil {
+				return string(data)
+			}
+		}
+		return redactText(str)
+	}
+
+	var buffer strings.Builder
+	r := []rune(str)
+	begin := 0
+	for i := 0; i < len(r); i++ {
+		if r[i] == '"' || r[i] == '\'' {
+			i = skipQuoted(r, i)
+		} else if r[i] == '{' {
+			end := getDocEnd(r, i)
+			prefix := string(r[begin:i])
+			buffer.WriteString(redactText(prefix))
+			if redactKeptDocMatched.MatchString(prefix) == true {
+				buffer.WriteString(string(r[i:end]))
+			} else {
+				buffer.WriteString(redactShellDoc(string(r[i:end])))
+			}
+			begin = end
+			i = end - 1
+		}
+	}
+	buffer.WriteString(redactText(string(r[begin:])))
+	return buffer.String()
+}
+
+// redactAttr redacts attributes of a structured log, documents are redacted and strings are searched for hosts
+func redactAttr(attr interface{}) interface{} {
+	doc, ok := attr.(bson.D)
+	if ok == false {
+		return attr
+	}
+	for i, e := range doc {
+		switch v := e.Value.(type) {
+		case string:
+			if redactHostAttrs[e.Key] == true {
+				doc[i].Value = redactHost(v)
+			} else {
+				doc[i].Value = redactText(v)
+			}
+		case bson.D, primitive.A:
+			if redactKeptAttrs[e.Key] == false {
+				doc[i].Value = redactValue(v, 0)
+			}
+		}
+	}
+	return doc
+}
+
+// redactValue replaces literals of a value, the string of the first key of a command is kept
+func redactValue(v interface{}, depth int) interface{} {
+	switch d := v.(type) {
+	case bson.D:
+		for i, e := range d {
+			if redactKeptKeys[e.Key] == true {
+				continue
+			} else if depth == 0 && i == 0 && redactCommandKeys[e.Key] == true { // e.g. find: "collection"
+				continue
+			}
+			d[i].Value = redactValue(e.Value, depth+1)
+		}
+		return d
+	case primitive.A:
+		for i, e := range d {
+			d[i] = redactValue(e, depth+1)
+		}
+		return d
+	case string:
+		return redactString(d)
+	case int32:
+		return int32(redactInt(int64(d), 32))
+	case int64:
+		return redactInt(d, 64)
+	case float64:
+		f, _ := strconv.ParseFloat(redactNumber(strconv.FormatFloat(d, 'f', -1, 64)), 64)
+		return f
+	case primitive.DateTime:
+		return primitive.DateTime(0)
+	case primitive.ObjectID:
+		oid, _ := primitive.ObjectIDFromHex(getRedactHash(d.Hex(), 24))
+		return oid
+	case primitive.Regex:
+		return primitive.Regex{Pattern: redactString(d.Pattern), Options: d.Options}
+	case primitive.Binary:
+		data, _ := hex.DecodeString(getRedactHash(string(d.Data), 2*len(d.Data)))
+		return primitive.Binary{Subtype: d.Subtype, Data: data}
+	}
+	return v
+}
+
+// redactShellDoc replaces literals of a document in mongo shell syntax, formats are kept
+func redactShellDoc(doc string) string {
+	type container struct {
+		isDoc bool
+		keep  bool
+	}
+	var buffer strings.Builder
+	r := []rune(doc)
+	stack := []container{}
+	expectKey := false
+	key := ""
+	nkeys := 0 // keys of the top level document
+	isKept := func() bool {
+		for _, c := range stack {
+			if c.keep == true {
+				return true
+			}
+		}
+		return redactKeptKeys[key] || (len(stack) == 1 && nkeys == 1 && redactCommandKeys[key])
+	}
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case c == '{' || c == '[':
+			stack = append(stack, container{isDoc: c == '{', keep: len(stack) > 0 && isKept()})
+			expectKey = c == '{'
+			buffer.WriteRune(c)
+			i++
+		case c == '}' || c == ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			buffer.WriteRune(c)
+			i++
+		case c == ',':
+			expectKey = len(stack) > 0 && stack[len(stack)-1].isDoc
+			buffer.WriteRune(c)
+			i++
+		case c == ':' || unicode.IsSpace(c):
+			if c == ':' {
+				expectKey = false
+			}
+			buffer.WriteRune(c)
+			i++
+		case c == '"' || c == '\'':
+			end := skipQuoted(r, i)
+			if expectKey == true {
+				key = string(r[i+1 : end])
+				nkeys += btoi(len(stack) == 1)
+				buffer.WriteString(string(r[i : end+1]))
+			} else if isKept() {
+				buffer.WriteString(string(r[i : end+1]))
+			} else {
+				buffer.WriteString(string(c) + redactString(string(r[i+1:end])) + string(c))
+			}
+			i = end + 1
+		case c == '/' && expectKey == false:
+			end := i + 1
+			for ; end < len(r) && r[end] != '/'; end++ {
+				if r[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(r) {
+				buffer.WriteString(string(r[i:]))
+			} else if isKept() {
+				buffer.WriteString(string(r[i : end+1]))
+			} else {
+				buffer.WriteString("/" + redactString(string(r[i+1:end])) + "/")
+			}
+			i = end + 1
+		default:
+			end := i
+			for end < len(r) && strings.ContainsRune("{}[]:,()\"' \t", r[end]) == false {
+				end++
+			}
+			if end == i { // unexpected parenthesis
+				buffer.WriteRune(c)
+				i++
+				continue
+			}
+			word := string(r[i:end])
+			if expectKey == true {
+				key = word
+				nkeys += btoi(len(stack) == 1)
+				buffer.WriteString(word)
+			} else if end < len(r) && r[end] == '(' { // constructors, e.g. ObjectId('...')
+				rparen := getCallEnd(r, end)
+				args := string(r[end+1 : rparen])
+				if isKept() == false {
+					args = redactCallArgs(word, args)
+				}
+				buffer.WriteString(word + "(" + args + ")")
+				end = rparen + 1
+			} else if _, err := strconv.ParseFloat(word, 64); err == nil && isKept() == false {
+				buffer.WriteString(redactNumber(word))
+			} else {
+				buffer.WriteString(word)
+			}
+			i = end
+		}
+	}
+	return buffer.String()
+}
+
+// redactCallArgs replaces arguments of constructors, Timestamp and UUID are kept
+func redactCallArgs(name string, args string) string {
+	switch name {
+	case "Timestamp", "UUID":
+		return args
+	case "Date", "ISODate":
+		if _, err := strconv.ParseInt(args, 10, 64); err == nil {
+			return "0"
+		}
+		return `"1970-01-01T00:00:00.000Z"`
+	case "BinData":
+		if i := strings.Index(args, ","); i > 0 {
+			data := strings.Trim(args[i+1:], ` "'`)
+			return args[:i] + ", " + base64.StdEncoding.EncodeToString([]byte(getRedactHash(data, 16)))
+		}
+	}
+	return redactShellDoc(args)
+}
+
+// redactString replaces a string by its type, e.g. an email address becomes 1a2b3c4d5e@redacted.com
+func redactString(str string) string {
+	switch getMetaType(str) {
+	case metaEmail:
+		return getRedactHash(str, 10) + "@redacted.com"
+	case metaIP:
+		return redactIP(str)
+	case metaSSN:
+		return "XXX-XX-XXXX"
+	case metaTEL:
+		return "(555) 555-" + redactNumber(getRedactDigits(str, 4))
+	case metaDate:
+		return "1970-01-01T00:00:00.000Z"
+	case metaOID:
+		return getRedactHash(str, 24)
+	}
+	if str == "" || strings.HasPrefix(str, "$") { // field paths and variables
+		return str
+	}
+	return getRedactHash(str, 12)
+}
+
+// redactNumber replaces digits of a number, the sign, decimal point and number of digits are kept
+func redactNumber(str string) string {
+	digits := getRedactDigits(str, len(str))
+	r := []rune(str)
+	n := 0
+	for i, c := range r {
+		if unicode.IsDigit(c) {
+			r[i] = rune(digits[n])
+			if i == 0 || (i == 1 && r[0] == '-') {
+				if r[i] == '0' && len(r) > i+1 && unicode.IsDigit(r[i+1]) {
+					r[i] = '1'
+				}
+			}
+			n++
+		}
+	}
+	return string(r)
+}
+
+// redactInt replaces digits of an integer, out of range values are clamped to the range of bitSize
+func redactInt(n int64, bitSize int) int64 {
+	str := redactNumber(strconv.FormatInt(n, 10))
+	i, err := strconv.ParseInt(str, 10, bitSize)
+	if err != nil && strings.HasPrefix(str, "-") {
+		return -1 << uint(bitSize-1)
+	} else if err != nil {
+		return 1<<uint(bitSize-1) - 1
+	}
+	return i
+}
+
+// redactText replaces email addresses, connection strings, IP addresses and host names of a text
+func redactText(str string) string {
+	str = redactURIMatched.ReplaceAllStringFunc(str, func(s string) string { return "mongodb://" + getRedactHash(s, 12) })
+	str = redactEmailMatched.ReplaceAllStringFunc(str, redactString)
+	str = redactHostKeyMatched.ReplaceAllStringFunc(str, func(s string) string { return "host=" + redactHost(s[5:]) })
+	str = redactHostPortMatched.ReplaceAllStringFunc(str, redactHost)
+	return redactIPMatched.ReplaceAllStringFunc(str, redactIP)
+}
+
+// redactHost replaces a host name or an IP address, the port is kept
+func redactHost(str string) string {
+	host, port := str, ""
+	if i := strings.LastIndex(str, ":"); i > 0 {
+		host, port = str[:i], str[i:]
+	}
+	if isIP(host) == true {
+		return redactIP(host) + port
+	}
+	return "host-" + getRedactHash(host, 8) + port
+}
+
+// redactIP replaces an IP address with one of 10.0.0.0/8
+func redactIP(str string) string {
+	sum := sha256.Sum256([]byte(str))
+	return fmt.Sprintf("10.%d.%d.%d", sum[0], sum[1], sum[2])
+}
+
+func getRedactHash(str string, n int) string {
+	sum := sha256.Sum256([]byte(str))
+	h := hex.EncodeToString(sum[:])
+	for len(h) < n {
+		h += h
+	}
+	return h[:n]
+}
+
+func getRedactDigits(str string, n int) string {
+	sum := sha256.Sum256([]byte(str))
+	digits := ""
+	for len(digits) < n {
+		for _, b := range sum {
+			digits += strconv.Itoa(int(b) % 10)
+		}
+	}
+	return digits[:n]
+}
+
+// skipQuoted returns position of the closing quote
+func skipQuoted(r []rune, i int) int {
+	j := i + 1
+	for ; j < len(r) && r[j] != r[i]; j++ {
+		if r[j] == '\\' {
+			j++
+		}
+	}
+	if j >= len(r) {
+		return len(r) - 1
+	}
+	return j
+}
+
+// getDocEnd returns position after the balanced closing brace
+func getDocEnd(r []rune, i int) int {
+	depth := 0
+	for j := i; j < len(r); j++ {
+		if r[j] == '"' || r[j] == '\'' {
+			j = skipQuoted(r, j)
+		} else if r[j] == '{' {
+			depth++
+		} else if r[j] == '}' {
+			if depth--; depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(r)
+}
+
+// getCallEnd returns position of the closing parenthesis
+func getCallEnd(r []rune, i int) int {
+	depth := 0
+	for j := i; j < len(r); j++ {
+		if r[j] == '"' || r[j] == '\'' {
+			j = skipQuoted(r, j)
+		} else if r[j] == '(' {
+			depth++
+		} else if r[j] == ')' {
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(r) - 1
+}
+
+func btoi(b bool) int {
+	if b == true {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var legacyLogLines = []string{
+	`2019-03-01T10:00:00.000+0000 I CONTROL  [initandlisten] MongoDB starting : pid=1 port=27017 dbpath=/data/db 64-bit host=db1.example.com`,
+	`2019-03-01T10:00:01.000+0000 I COMMAND  [conn1] command keyhole.users command: find { find: "users", filter: { email: "ken.chen@simagix.com", account: 12345678, since: { $gte: new Date(1546300800000) } }, sort: { name: -1 }, limit: 10, $db: "keyhole" } planSummary: IXSCAN { email: 1 } keysExamined:1 docsExamined:1 nreturned:1 reslen:300 locks:{ Global: { acquireCount: { r: 1 } } } protocol:op_msg 150ms`,
+	`2019-03-01T10:00:02.000+0000 I COMMAND  [conn2] command keyhole.users command: aggregate { aggregate: "users", pipeline: [ { $match: { _id: ObjectId('5c7a1c2e9b1e8a3f4c2d1e0f'), name: /^Ken/i } } ], cursor: {}, $db: "keyhole" } planSummary: COLLSCAN keysExamined:0 docsExamined:5000 nreturned:1 reslen:300 12000ms`,
+	`2019-03-01T10:00:03.000+0000 I NETWORK  [listener] connection accepted from 10.1.2.3:52312 #1 (1 connection now open)`,
+}
+
+func TestRedactLegacyLogLine(t *testing.T) {
+	str := RedactLogLine(legacyLogLines[1])
+	for _, s := range []string{"ken.chen@simagix.com", "12345678", "1546300800000"} {
+		if strings.Index(str, s) >= 0 {
+			t.Fatal(s, "not redacted", str)
+		}
+	}
+	for _, s := range []string{`find: "users"`, "sort: { name: -1 }", "limit: 10", "planSummary: IXSCAN { email: 1 }",
+		"locks:{ Global: { acquireCount: { r: 1 } } }", "nreturned:1", "150ms", "@redacted.com"} {
+		if strings.Index(str, s) < 0 {
+			t.Fatal(s, "not found", str)
+		}
+	}
+	if str != RedactLogLine(legacyLogLines[1]) {
+		t.Fatal("not deterministic")
+	}
+	if str = RedactLogLine(legacyLogLines[0]); strings.Index(str, "db1.example.com") >= 0 || strings.Index(str, "host=host-") < 0 {
+		t.Fatal(str)
+	}
+	if str = RedactLogLine(legacyLogLines[3]); strings.Index(str, "10.1.2.3") >= 0 || strings.Index(str, ":52312") < 0 {
+		t.Fatal(str)
+	}
+}
+
+func TestRedactJSONLogLine(t *testing.T) {
+	str := RedactLogLine(jsonLogLines[1])
+	if strings.Index(str, `"Red"`) >= 0 || strings.Index(str, "2010") >= 0 || strings.Index(str, `"find":"cars"`) < 0 {
+		t.Fatal(str)
+	}
+	rec, ok := parseJSONLogLine(str)
+	if ok == false || rec.milli != 150 || rec.keysExamined != 120 || rec.index != "{ color: 1 }" {
+		t.Fatal(rec)
+	}
+	if str = RedactLogLine(connLogLines[6]); strings.Index(str, "10.0.0.1") >= 0 {
+		t.Fatal(str)
+	}
+}
+
+func TestRedactPreservesShapes(t *testing.T) {
+	lines := append(append([]string{}, legacyLogLines...), jsonLogLines[1:]...)
+	for _, line := range lines {
+		var before, after logRecord
+		var ok bool
+		if strings.HasPrefix(line, "{") {
+			before, ok = parseJSONLogLine(line)
+			after, _ = parseJSONLogLine(RedactLogLine(line))
+		} else {
+			before, ok = parseLegacyLogLine(line)
+			after, _ = parseLegacyLogLine(RedactLogLine(line))
+		}
+		if ok == false {
+			continue
+		}
+		bshape, _ := GetQueryShape(before.filter)
+		ashape, _ := GetQueryShape(after.filter)
+		if bshape != ashape || before.milli != after.milli || before.scan != after.scan || before.index != after.index {
+			t.Fatal(bshape, ashape, before, after)
+		}
+	}
+}
+
+func TestRedactIntegerRanges(t *testing.T) {
+	for _, v := range []interface{}{int32(math.MaxInt32), int32(math.MinInt32), int32(1999999999), int64(math.MaxInt64),
+		int64(math.MinInt64), int64(999999999999999999)} {
+		r := redactValue(v, 1)
+		switch n := r.(type) {
+		case int32:
+			if (n < 0) != (v.(int32) < 0) || len(strconv.Itoa(int(n))) != len(strconv.Itoa(int(v.(int32)))) {
+				t.Fatal(v, r)
+			}
+		case int64:
+			if (n < 0) != (v.(int64) < 0) || len(strconv.FormatInt(n, 10)) != len(strconv.FormatInt(v.(int64), 10)) {
+				t.Fatal(v, r)
+			}
+		default:
+			t.Fatal("type changed", v, r)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	filename := "log_redactor_test.log"
+	outfile := "log_redactor_test-redacted.log"
+	ioutil.WriteFile(filename, []byte(strings.Join(legacyLogLines, "\n")+"\n"), 0644)
+	defer os.Remove(filename)
+	defer os.Remove(outfile)
+	lr := NewLogRedactor(filename)
+	lr.SetSilent(true)
+	if err := lr.Redact(outfile); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(outfile)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != len(legacyLogLines) {
+		t.Fatal(string(data))
+	}
+}
@@ -8,14 +8,17 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // COLLSCAN constance
@@ -23,42 +26,81 @@ const COLLSCAN = "COLLSCAN"
 
 // LogInfo keeps loginfo struct
 type LogInfo struct {
+	Host           string // host:port, or hosts of merged results
 	OpsPatterns    []OpPerformanceDoc
 	OutputFilename string
+	PlanFlips      []PlanFlipDoc // query shapes executed by more than one plan
 	SlowOps        []SlowOps
+	Transactions   TxnStatsDoc // slow transactions and transaction commands
+	Version        int         // version of the encoded output
 	collscan       bool
+	examples       int // slowest lines kept per pattern
 	filename       string
+	filter         *LogFilter
+	histogram      bool
+	interval       int // seconds
 	mongoInfo      string
 	silent         bool
+	slowMilli      int // ops slower than are listed
+	top            int // number of slowest ops listed
 	verbose        bool
 }
 
 // OpPerformanceDoc stores performance data
 type OpPerformanceDoc struct {
-	Command    string // count, delete, find, remove, and update
-	Count      int    // number of ops
-	Filter     string // query pattern
-	MaxMilli   int    // max millisecond
-	Namespace  string // database.collectin
-	Scan       string // COLLSCAN
-	TotalMilli int    // total milliseconds
-	Index      string // index used
+	Command    string                 `json:"command"`            // count, delete, find, remove, and update
+	Count      int                    `json:"count"`              // number of ops
+	Filter     string                 `json:"filter"`             // query pattern
+	Hash       string                 `json:"hash"`               // query shape hash
+	MaxMilli   int                    `json:"maxMilli"`           // max millisecond
+	Namespace  string                 `json:"ns"`                 // database.collectin
+	Scan       string                 `json:"scan,omitempty"`     // COLLSCAN
+	TotalMilli int                    `json:"totalMilli"`         // total milliseconds
+	Index      string                 `json:"index,omitempty"`    // index used
+	Buckets    map[int64]OpBucketDoc  `json:"buckets,omitempty"`  // by epoch seconds of beginnings of intervals
+	Examples   []SlowOps              `json:"examples,omitempty"` // slowest log lines
+	Hosts      map[string]OpBucketDoc `json:"hosts,omitempty"`    // by hosts of merged results
+
+	BytesRead      int `json:"bytesRead"`      // storage bytes read
+	DocsExamined   int `json:"docsExamined"`   // documents examined
+	KeysExamined   int `json:"keysExamined"`   // index keys examined
+	LockWaitMicros int `json:"lockWaitMicros"` // microseconds waiting for locks
+	NReturned      int `json:"nreturned"`      // documents returned
+	NumYields      int `json:"numYields"`      // yields
+	ReadMicros     int `json:"readMicros"`     // microseconds reading from storage
+	SortStages     int `json:"sortStages"`     // ops with in-memory sort stages
+	TxnOps         int `json:"txnOps"`         // ops in multi-document transactions
+	WriteConflicts int `json:"writeConflicts"` // write conflicts retried
+
+	BroadcastOps    int `json:"broadcastOps,omitempty"`    // exhaustive ops (mongos)
+	MaxShards       int `json:"maxShards,omitempty"`       // max shards targeted by an op (mongos)
+	MinShards       int `json:"minShards,omitempty"`       // min shards targeted by an op (mongos)
+	RemoteWaitMilli int `json:"remoteWaitMilli,omitempty"` // milliseconds waiting for shards (mongos)
+	RoutedOps       int `json:"routedOps,omitempty"`       // ops with shards targeted (mongos)
+	ShardsTargeted  int `json:"shardsTargeted,omitempty"`  // total shards targeted (mongos)
 }
 
 // SlowOps holds slow ops log and time
 type SlowOps struct {
-	Milli int
-	Log   string
+	Milli int    `json:"milli"`
+	Log   string `json:"log"`
 }
 
 // NewLogInfo -
 func NewLogInfo(filename string) *LogInfo {
-	li := LogInfo{filename: filename, collscan: false, silent: false, verbose: false}
+	li := LogInfo{filename: filename, collscan: false, interval: 60, silent: false,
+		slowMilli: 10000, top: 10, verbose: false}
 	li.OutputFilename = filepath.Base(filename)
+	if i := strings.IndexAny(li.OutputFilename, "*?["); i >= 0 { // globs, e.g. mongod.log*
+		if li.OutputFilename = strings.TrimRight(li.OutputFilename[:i], "._-"); li.OutputFilename == "" {
+			li.OutputFilename = "loginfo"
+		}
+	}
 	if strings.HasSuffix(li.OutputFilename, ".gz") {
 		li.OutputFilename = li.OutputFilename[:len(li.OutputFilename)-3]
 	}
 	li.OutputFilename += ".enc"
+	li.Version = logInfoVersion
 	return &li
 }
 
@@ -67,6 +109,38 @@ func (li *LogInfo) SetCollscan(collscan bool) {
 	li.collscan = collscan
 }
 
+// SetExamples sets number of slowest log lines kept per pattern
+func (li *LogInfo) SetExamples(examples int) {
+	li.examples = examples
+}
+
+// SetFilter sets filters applied before aggregation
+func (li *LogInfo) SetFilter(filter *LogFilter) {
+	li.filter = filter
+}
+
+// SetHistogram -
+func (li *LogInfo) SetHistogram(histogram bool) {
+	li.histogram = histogram
+}
+
+// SetInterval sets seconds of time buckets
+func (li *LogInfo) SetInterval(interval int) {
+	if interval > 0 {
+		li.interval = interval
+	}
+}
+
+// SetSlowMilli sets threshold of listed slowest ops
+func (li *LogInfo) SetSlowMilli(milli int) {
+	li.slowMilli = milli
+}
+
+// SetTop sets number of listed slowest ops
+func (li *LogInfo) SetTop(top int) {
+	li.top = top
+}
+
 // SetSilent -
 func (li *LogInfo) SetSilent(silent bool) {
 	li.silent = silent
@@ -107,313 +181,477 @@ func GetDocByField(str string, field string) string {
 	return str[bpos:epos]
 }
 
-func getConfigOptions(reader *bufio.Reader) []string {
-	matched := regexp.MustCompile(`^\S+ .? CONTROL\s+\[\w+\] (\w+(:)?) (.*)$`)
-	var err error
-	var buf []byte
-	var strs []string
+var configOptionsMatched = regexp.MustCompile(`^\S+ .? CONTROL\s+\[\w+\] (\w+(:)?) (.*)$`)
+var configKeyMatched = regexp.MustCompile(`((\S+):)`)
 
-	for {
-		buf, _, err = reader.ReadLine() // 0x0A separator = newline
-		if err != nil {
-			break
-		} else if matched.MatchString(string(buf)) == true {
-			result := matched.FindStringSubmatch(string(buf))
-			if result[1] == "db" {
-				s := "db " + result[3]
-				strs = append(strs, s)
-			} else if result[1] == "options:" {
-				re := regexp.MustCompile(`((\S+):)`)
-				body := re.ReplaceAllString(result[3], "\"$1\":")
-				var buf bytes.Buffer
-				json.Indent(&buf, []byte(body), "", "  ")
-
-				strs = append(strs, "config options:")
-				strs = append(strs, string(buf.Bytes()))
-				return strs
-			}
-		}
+// appendConfigOptions appends version and config options of a log line, returns true once options are found
+func appendConfigOptions(strs []string, line string) ([]string, bool) {
+	if len(line) > 0 && line[0] == '{' {
+		return appendJSONConfigOptions(strs, []byte(line))
+	} else if strings.Index(line, "CONTROL") < 0 || configOptionsMatched.MatchString(line) == false {
+		return strs, false
+	}
+	result := configOptionsMatched.FindStringSubmatch(line)
+	if result[1] == "db" {
+		strs = append(strs, "db "+result[3])
+	} else if result[1] == "options:" {
+		body := configKeyMatched.ReplaceAllString(result[3], "\"$1\":")
+		var buf bytes.Buffer
+		json.Indent(&buf, []byte(body), "", "  ")
+		strs = append(strs, "config options:")
+		strs = append(strs, string(buf.Bytes()))
+		return strs, true
 	}
-	return strs
+	return strs, false
 }
 
 // Analyze -
 func (li *LogInfo) Analyze() (string, error) {
-	err := li.Parse()
+	err := li.Load()
 	if err != nil {
 		return "", err
 	}
+	if strings.HasSuffix(li.filename, ".enc") == false {
+		if err = li.Save(); err != nil {
+			log.Println("encode error:", err)
+		}
+	}
+	return li.GetSummary(), nil
+}
+
+// GetSummary returns slowest ops and query patterns
+func (li *LogInfo) GetSummary() string {
 	summaries := []string{}
 	if li.verbose == true {
 		summaries = append([]string{}, li.mongoInfo)
 	}
 	if len(li.SlowOps) > 0 {
-		summaries = append(summaries, fmt.Sprintf("Ops slower than 10 seconds (list top %d):", len(li.SlowOps)))
+		summaries = append(summaries, fmt.Sprintf("Ops slower than %s (list top %d):", strings.TrimSpace(MilliToTimeString(float64(li.slowMilli))), len(li.SlowOps)))
 		for _, op := range li.SlowOps {
 			summaries = append(summaries, MilliToTimeString(float64(op.Milli))+" => "+op.Log)
 		}
 		summaries = append(summaries, "\n")
 	}
 	summaries = append(summaries, printLogsSummary(li.OpsPatterns))
-	var data bytes.Buffer
-	enc := gob.NewEncoder(&data)
-	if err = enc.Encode(li); err != nil {
-		log.Println("encode error:", err)
+	if str := li.GetBroadcastPatterns(); str != "" {
+		summaries = append(summaries, str)
+	}
+	if strings.Index(li.Host, ",") > 0 {
+		summaries = append(summaries, li.GetHostsBreakdown())
+	}
+	if len(li.PlanFlips) > 0 {
+		summaries = append(summaries, li.GetPlanFlips())
+	}
+	if str := li.GetTransactionsSummary(); str != "" {
+		summaries = append(summaries, str)
+	}
+	if li.histogram == true {
+		summaries = append(summaries, li.GetHistogram(10))
 	}
-	ioutil.WriteFile(li.OutputFilename, data.Bytes(), 0644)
-	return strings.Join(summaries, "\n"), nil
+	if li.examples > 0 {
+		summaries = append(summaries, li.GetExamples())
+	}
+	return strings.Join(summaries, "\n")
 }
 
-// Parse -
-func (li *LogInfo) Parse() error {
-	var err error
-	var reader *bufio.Reader
-	var file *os.File
-	var opsMap map[string]OpPerformanceDoc
+// GetExamples returns slowest log lines of patterns
+func (li *LogInfo) GetExamples() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("Slowest %d log lines of patterns:\n", li.examples))
+	for _, doc := range li.OpsPatterns {
+		if len(doc.Examples) == 0 {
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("\n%s %s %s\n", doc.Command, doc.Namespace, doc.Filter))
+		for _, op := range doc.Examples {
+			buffer.WriteString(MilliToTimeString(float64(op.Milli)) + " => " + op.Log + "\n")
+		}
+	}
+	return buffer.String()
+}
 
-	opsMap = make(map[string]OpPerformanceDoc)
-	if file, err = os.Open(li.filename); err != nil {
+// Load decodes an encoded result (.enc) or parses a log file
+func (li *LogInfo) Load() error {
+	if strings.HasSuffix(li.filename, ".enc") == false {
+		return li.Parse()
+	}
+	data, err := ioutil.ReadFile(li.filename)
+	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	if reader, err = NewReader(file); err != nil {
+	li.Version = 0 // not in outputs of earlier versions
+	if err = gob.NewDecoder(bytes.NewBuffer(data)).Decode(li); err != nil {
 		return err
 	}
-	lineCounts, _ := CountLines(reader)
+	if li.Version != logInfoVersion {
+		return fmt.Errorf("unsupported version %d of %s, analyze the log file again", li.Version, li.filename)
+	}
+	return nil
+}
 
-	file.Seek(0, 0)
-	reader, _ = NewReader(file)
-	var buffer bytes.Buffer
-	if strs := getConfigOptions(reader); len(strs) > 0 {
-		for _, s := range strs {
-			buffer.WriteString(s + "\n")
-		}
+// Save writes encoded result to OutputFilename
+func (li *LogInfo) Save() error {
+	var data bytes.Buffer
+	li.Version = logInfoVersion
+	if err := gob.NewEncoder(&data).Encode(li); err != nil {
+		return err
 	}
-	li.mongoInfo = buffer.String()
+	return ioutil.WriteFile(li.OutputFilename, data.Bytes(), 0644)
+}
+
+// logRecord is a slow op parsed from a log line
+type logRecord struct {
+	opMetrics
+	filter    string
+	index     string
+	line      string
+	milli     int
+	ns        string
+	op        string
+	scan      string
+	timestamp time.Time
+}
+
+const legacyTimeLayout = "2006-01-02T15:04:05.000Z0700"
 
-	matched := regexp.MustCompile(`^\S+ \S+\s+(\w+)\s+\[\w+\] (\w+) (\S+) \S+: (.*) (\d+)ms$`) // SERVER-37743
-	file.Seek(0, 0)
-	if reader, err = NewReader(file); err != nil {
+var legacyLogMatched = regexp.MustCompile(`^\S+ \S+\s+(\w+)\s+\[\w+\] (\w+) (\S+) \S+: (.*) (\d+)ms$`) // SERVER-37743
+var legacyCommandMatched = regexp.MustCompile(`^(\w+) ({.*})$`)
+
+// Parse reads log files of a file, a glob or a directory once, in order of their first timestamps, as one stream.
+// Lines are parsed and aggregated by a pool of workers.
+func (li *LogInfo) Parse() error {
+	var err error
+	var filenames []string
+	var totalSize, doneSize int64
+
+	if filenames, err = GetLogFilenames(li.filename); err != nil {
 		return err
 	}
-	index := 0
-	for {
-		if index%25 == 1 && li.silent == false {
-			fmt.Fprintf(os.Stderr, "\r%3d%% ", (100*index)/lineCounts)
-		}
-		var buf []byte
-		var isPrefix bool
-		buf, isPrefix, err = reader.ReadLine() // 0x0A separator = newline
-		str := string(buf)
-		for isPrefix == true {
-			var bbuf []byte
-			bbuf, isPrefix, err = reader.ReadLine()
-			str += string(bbuf)
-		}
-		index++
-		scan := ""
+	for _, filename := range filenames {
+		fileInfo, err := os.Stat(filename)
 		if err != nil {
-			break
-		} else if matched.MatchString(str) == true {
-			if strings.Index(str, "COLLSCAN") >= 0 {
-				scan = COLLSCAN
-			}
-			if li.collscan == true && scan != COLLSCAN {
-				continue
-			}
-			result := matched.FindStringSubmatch(str)
-			isFound := false
-			bpos := 0 // begin position
-			epos := 0 // end position
-			for _, r := range result[4] {
-				epos++
-				if isFound == false && r == '{' {
-					isFound = true
-					bpos++
-				} else if isFound == true {
-					if r == '{' {
-						bpos++
-					} else if r == '}' {
-						bpos--
-					}
-				}
-
-				if isFound == true && bpos == 0 {
-					break
-				}
-			}
+			return err
+		}
+		totalSize += fileInfo.Size()
+	}
 
-			re := regexp.MustCompile(`^(\w+) ({.*})$`)
-			op := result[2]
-			ns := result[3]
-			if ns == "local.oplog.rs" || strings.HasSuffix(ns, ".$cmd") == true {
-				continue
-			}
-			filter := result[4][:epos]
-			ms := result[5]
-			if op == "command" {
-				idx := strings.Index(filter, "command: ")
-				if idx > 0 {
-					filter = filter[idx+len("command: "):]
+	workers := runtime.NumCPU()
+	batches := make(chan []string, 2*workers)
+	aggs := make([]*logAggregator, workers)
+	wg := NewWaitGroup(workers)
+	for i := range aggs {
+		aggs[i] = newLogAggregator(li)
+		wg.Add(1)
+		go func(agg *logAggregator) {
+			defer wg.Done()
+			for lines := range batches {
+				agg.batch++
+				for _, str := range lines { // format of each line, rotated logs may span upgrades
+					li.parseLogLine(agg, str, strings.HasPrefix(str, "{"))
 				}
-				res := re.FindStringSubmatch(filter)
-				if len(res) < 3 {
-					continue
-				}
-				op = res[1]
-				filter = res[2]
 			}
+		}(aggs[i])
+	}
 
-			if hasFilter(op) == false {
-				continue
-			}
-			if op == "delete" && strings.Index(filter, "writeConcern:") >= 0 {
-				continue
-			} else if op == "find" {
-				nstr := "{ }"
-				s := GetDocByField(filter, "filter: ")
-				if s != "" {
-					nstr = s
-				}
-				s = GetDocByField(filter, "sort: ")
-				if s != "" {
-					nstr = nstr + ", sort: " + s
-				}
-				filter = nstr
-			} else if op == "count" || op == "distinct" {
-				nstr := ""
-				s := GetDocByField(filter, "query: ")
-				if s != "" {
-					nstr = s
-				}
-				filter = nstr
-			} else if op == "delete" || op == "update" || op == "remove" {
-				var s string
-				// if result[1] == "WRITE" {
-				if strings.Index(filter, "query: ") >= 0 {
-					s = GetDocByField(filter, "query: ")
-				} else {
-					s = GetDocByField(filter, "q: ")
-				}
-				if s != "" {
-					filter = s
-				}
-			} else if op == "aggregate" || (op == "getmore" && strings.Index(filter, "pipeline:") > 0) {
-				nstr := ""
-				s := ""
-				for _, mstr := range []string{"pipeline: [ { $match: ", "pipeline: [ { $sort: "} {
-					s = GetDocByField(result[4], mstr)
-					if s != "" {
-						nstr = s
-						filter = nstr
-						break
-					}
-				}
-				if s == "" {
-					if scan == "COLLSCAN" { // it's a collection scan without $match or $sort
-						filter = "{}"
-					} else {
-						continue
-					}
-				}
-			} else if op == "getMore" || op == "getmore" {
-				nstr := ""
-				s := GetDocByField(result[4], "originatingCommand: ")
-
-				if s != "" {
-					for _, mstr := range []string{"filter: ", "pipeline: [ { $match: ", "pipeline: [ { $sort: "} {
-						s = GetDocByField(result[4], mstr)
-						if s != "" {
-							nstr = s
-							filter = nstr
-							break
-						}
-					}
-					if s == "" {
-						continue
-					}
-				} else {
-					continue
-				}
-			}
-			index := GetDocByField(str, "planSummary: IXSCAN")
-			if index == "" && strings.Index(str, "planSummary: EOF") >= 0 {
-				index = "EOF"
+	var strs []string
+	isConfigFound := false
+	lines := make([]string, 0, logBatchSize)
+	for _, filename := range filenames {
+		var file *os.File
+		var reader *bufio.Reader
+		var counter *ByteCounter
+		if file, err = os.Open(filename); err != nil {
+			break
+		}
+		if reader, counter, err = NewCountingReader(file); err != nil {
+			file.Close()
+			break
+		}
+		for {
+			var buf []byte
+			var isPrefix bool
+			buf, isPrefix, err = reader.ReadLine() // 0x0A separator = newline
+			str := string(buf)
+			for isPrefix == true {
+				var bbuf []byte
+				bbuf, isPrefix, err = reader.ReadLine()
+				str += string(bbuf)
 			}
-			if index == "" && strings.Index(str, "planSummary: IDHACK") >= 0 {
-				index = "IDHACK"
+			if err != nil {
+				break
 			}
-			if scan == "" && strings.Index(str, "planSummary: COUNT_SCAN") >= 0 {
-				index = "COUNT_SCAN"
+			if isConfigFound == false {
+				strs, isConfigFound = appendConfigOptions(strs, str)
 			}
-			filter = removeInElements(filter, "$in: [ ")
-			filter = removeInElements(filter, "$nin: [ ")
-			filter = removeInElements(filter, "$in: [ ")
-			filter = removeInElements(filter, "$nin: [ ")
-
-			isRegex := strings.Index(filter, "{ $regex: ")
-			if isRegex >= 0 {
-				cnt := 0
-				for _, r := range filter[isRegex:] {
-					if r == '}' {
-						break
-					}
-					cnt++
-				}
-				filter = filter[:(isRegex+10)] + "/.../.../" + filter[(isRegex+cnt):]
+			if li.Host == "" {
+				li.Host = getStartupHost(str)
 			}
-
-			re = regexp.MustCompile(`(: "[^"]*"|: -?\d+(\.\d+)?|: new Date\(\d+?\)|: true|: false)`)
-			filter = re.ReplaceAllString(filter, ":1")
-			re = regexp.MustCompile(`, shardVersion: \[.*\]`)
-			filter = re.ReplaceAllString(filter, "")
-			re = regexp.MustCompile(`( ObjectId\('\S+'\))|(UUID\("\S+"\))|( Timestamp\(\d+, \d+\))|(BinData\(\d+, \S+\))`)
-			filter = re.ReplaceAllString(filter, "1")
-			re = regexp.MustCompile(`(: \/.*\/(.?) })`)
-			filter = re.ReplaceAllString(filter, ": /regex/$2}")
-			filter = strings.Replace(strings.Replace(filter, "{ ", "{", -1), " }", "}", -1)
-			key := op + "." + filter + "." + scan
-			_, ok := opsMap[key]
-			milli, _ := strconv.Atoi(ms)
-			if milli >= 10000 { // >= 10 seconds too slow, top 10
-				li.SlowOps = append(li.SlowOps, SlowOps{Milli: milli, Log: str})
-				if len(li.SlowOps) > 10 {
-					sort.Slice(li.SlowOps, func(i, j int) bool {
-						return li.SlowOps[i].Milli > li.SlowOps[j].Milli
-					})
-					li.SlowOps = li.SlowOps[:10]
+			if lines = append(lines, str); len(lines) == logBatchSize {
+				batches <- lines
+				lines = make([]string, 0, logBatchSize)
+				if li.silent == false && totalSize > 0 {
+					fmt.Fprintf(os.Stderr, "\r%3d%% ", 100*(doneSize+counter.Count())/totalSize)
 				}
 			}
-			if ok {
-				max := opsMap[key].MaxMilli
-				if milli > max {
-					max = milli
-				}
-				x := opsMap[key].TotalMilli + milli
-				y := opsMap[key].Count + 1
-				opsMap[key] = OpPerformanceDoc{Command: opsMap[key].Command, Namespace: ns, Filter: opsMap[key].Filter, MaxMilli: max, TotalMilli: x, Count: y, Scan: scan, Index: index}
-			} else {
-				opsMap[key] = OpPerformanceDoc{Command: op, Namespace: ns, Filter: filter, TotalMilli: milli, MaxMilli: milli, Count: 1, Scan: scan, Index: index}
-			}
 		}
+		doneSize += counter.Count()
+		file.Close()
+		if err != io.EOF {
+			break
+		}
+	}
+	batches <- lines
+	close(batches)
+	wg.Wait()
+	if err != io.EOF {
+		return err
+	}
+
+	li.mongoInfo = ""
+	for _, s := range strs {
+		li.mongoInfo += s + "\n"
+	}
+	if li.Host == "" {
+		li.Host = filepath.Base(li.filename)
+	}
+	agg := newLogAggregator(li)
+	for _, a := range aggs {
+		agg.merge(a)
+	}
+	li.setResults(agg)
+	if li.silent == false {
+		fmt.Fprintf(os.Stderr, "\r     \r")
 	}
+	return nil
+}
 
-	li.OpsPatterns = make([]OpPerformanceDoc, 0, len(opsMap))
-	for _, value := range opsMap {
+// setResults sets slowest ops and query patterns, the slowest average first, of an aggregator
+func (li *LogInfo) setResults(agg *logAggregator) {
+	li.SlowOps = sortSlowOps(agg.slowOps)
+	li.PlanFlips = agg.getPlanFlips()
+	li.Transactions = agg.txn
+	li.OpsPatterns = make([]OpPerformanceDoc, 0, len(agg.opsMap))
+	for _, value := range agg.opsMap {
+		value.Examples = sortSlowOps(value.Examples)
 		li.OpsPatterns = append(li.OpsPatterns, value)
 	}
 	sort.Slice(li.OpsPatterns, func(i, j int) bool {
 		return float64(li.OpsPatterns[i].TotalMilli)/float64(li.OpsPatterns[i].Count) > float64(li.OpsPatterns[j].TotalMilli)/float64(li.OpsPatterns[j].Count)
 	})
-	if li.silent == false {
-		fmt.Fprintf(os.Stderr, "\r     \r")
+}
+
+// parseLogLine parses a line and aggregates it if it passes filters
+func (li *LogInfo) parseLogLine(agg *logAggregator, str string, isJSON bool) {
+	var rec logRecord
+	var ok bool
+	if txn, ok := parseTxnLogLine(str, isJSON); ok {
+		if li.collscan == false && (li.filter == nil || li.filter.isTimeMatched(txn.timestamp, txn.milli)) {
+			agg.addTxn(txn)
+		}
+		return
 	}
-	return nil
+	if isJSON == true {
+		rec, ok = parseJSONLogLine(str)
+	} else {
+		rec, ok = parseLegacyLogLine(str)
+	}
+	if ok == false || li.isMatched(rec) == false {
+		return
+	}
+	agg.add(rec)
+}
+
+// isMatched returns true if a slow op passes --collscan and filters
+func (li *LogInfo) isMatched(rec logRecord) bool {
+	return (li.collscan == false || rec.scan == COLLSCAN) && (li.filter == nil || li.filter.isMatched(rec) == true)
+}
+
+// ParseLogLine returns command and filter, in mongo shell syntax, of a slow op of a log line
+func ParseLogLine(str string) (string, string, bool) {
+	var rec logRecord
+	var ok bool
+	if strings.HasPrefix(str, "{") {
+		rec, ok = parseJSONLogLine(str)
+	} else {
+		rec, ok = parseLegacyLogLine(str)
+	}
+	return rec.op, rec.filter, ok
+}
+
+// parseLegacyLogLine parses a slow op of the text log format
+func parseLegacyLogLine(str string) (logRecord, bool) {
+	rec := logRecord{line: str}
+	if legacyLogMatched.MatchString(str) == false {
+		return rec, false
+	}
+	if strings.Index(str, "COLLSCAN") >= 0 {
+		rec.scan = COLLSCAN
+	}
+	result := legacyLogMatched.FindStringSubmatch(str)
+	isFound := false
+	bpos := 0 // begin position
+	epos := 0 // end position
+	for _, r := range result[4] {
+		epos++
+		if isFound == false && r == '{' {
+			isFound = true
+			bpos++
+		} else if isFound == true {
+			if r == '{' {
+				bpos++
+			} else if r == '}' {
+				bpos--
+			}
+		}
+
+		if isFound == true && bpos == 0 {
+			break
+		}
+	}
+
+	op := result[2]
+	ns := result[3]
+	if ns == "local.oplog.rs" || strings.HasSuffix(ns, ".$cmd") == true {
+		return rec, false
+	}
+	filter := result[4][:epos]
+	if op == "command" {
+		idx := strings.Index(filter, "command: ")
+		if idx > 0 {
+			filter = filter[idx+len("command: "):]
+		}
+		res := legacyCommandMatched.FindStringSubmatch(filter)
+		if len(res) < 3 {
+			return rec, false
+		}
+		op = res[1]
+		filter = res[2]
+	}
+
+	if hasFilter(op) == false {
+		return rec, false
+	}
+	if op == "delete" && strings.Index(filter, "writeConcern:") >= 0 {
+		return rec, false
+	} else if op == "find" {
+		nstr := "{ }"
+		s := GetDocByField(filter, "filter: ")
+		if s != "" {
+			nstr = s
+		}
+		s = GetDocByField(filter, "sort: ")
+		if s != "" {
+			nstr = nstr + ", sort: " + s
+		}
+		filter = nstr
+	} else if op == "count" || op == "distinct" {
+		nstr := ""
+		s := GetDocByField(filter, "query: ")
+		if s != "" {
+			nstr = s
+		}
+		filter = nstr
+	} else if op == "delete" || op == "update" || op == "remove" {
+		var s string
+		if strings.Index(filter, "query: ") >= 0 {
+			s = GetDocByField(filter, "query: ")
+		} else {
+			s = GetDocByField(filter, "q: ")
+		}
+		if s != "" {
+			filter = s
+		}
+	} else if op == "aggregate" || (op == "getmore" && strings.Index(filter, "pipeline:") > 0) {
+		s := ""
+		for _, mstr := range []string{"pipeline: [ { $match: ", "pipeline: [ { $sort: "} {
+			s = GetDocByField(result[4], mstr)
+			if s != "" {
+				filter = s
+				break
+			}
+		}
+		if s == "" {
+			if rec.scan == COLLSCAN { // it's a collection scan without $match or $sort
+				filter = "{}"
+			} else {
+				return rec, false
+			}
+		}
+	} else if op == "getMore" || op == "getmore" {
+		s := GetDocByField(result[4], "originatingCommand: ")
+		if s == "" {
+			return rec, false
+		}
+		for _, mstr := range []string{"filter: ", "pipeline: [ { $match: ", "pipeline: [ { $sort: "} {
+			s = GetDocByField(result[4], mstr)
+			if s != "" {
+				filter = s
+				break
+			}
+		}
+		if s == "" {
+			return rec, false
+		}
+	}
+	rec.index = getIndexFromPlanSummary(str, rec.scan)
+	rec.opMetrics = getLegacyMetrics(str)
+	rec.op = op
+	rec.ns = ns
+	rec.filter = filter
+	rec.milli, _ = strconv.Atoi(result[5])
+	rec.timestamp, _ = time.Parse(legacyTimeLayout, str[:strings.Index(str, " ")])
+	return rec, true
+}
+
+// getIndexFromPlanSummary returns index used from a log line with planSummary
+func getIndexFromPlanSummary(str string, scan string) string {
+	index := GetDocByField(str, "planSummary: IXSCAN")
+	if index == "" && strings.Index(str, "planSummary: EOF") >= 0 {
+		index = "EOF"
+	}
+	if index == "" && strings.Index(str, "planSummary: IDHACK") >= 0 {
+		index = "IDHACK"
+	}
+	if scan == "" && strings.Index(str, "planSummary: COUNT_SCAN") >= 0 {
+		index = "COUNT_SCAN"
+	}
+	return index
+}
+
+var valuesMatched = regexp.MustCompile(`(: "[^"]*"|: -?\d+(\.\d+)?|: new Date\(\d+?\)|: true|: false)`)
+var shardVersionMatched = regexp.MustCompile(`, shardVersion: \[.*\]`)
+var constructorsMatched = regexp.MustCompile(`( ObjectId\('\S+'\))|(UUID\("\S+"\))|( Timestamp\(\d+, \d+\))|(BinData\(\d+, \S+\))`)
+var regexMatched = regexp.MustCompile(`(: \/.*\/(.?) })`)
+
+// normalizeFilter replaces values of a filter with placeholders
+func normalizeFilter(filter string) string {
+	filter = removeInElements(filter, "$in: [ ")
+	filter = removeInElements(filter, "$nin: [ ")
+	filter = removeInElements(filter, "$in: [ ")
+	filter = removeInElements(filter, "$nin: [ ")
+
+	isRegex := strings.Index(filter, "{ $regex: ")
+	if isRegex >= 0 {
+		cnt := 0
+		for _, r := range filter[isRegex:] {
+			if r == '}' {
+				break
+			}
+			cnt++
+		}
+		filter = filter[:(isRegex+10)] + "/.../.../" + filter[(isRegex+cnt):]
+	}
+
+	filter = valuesMatched.ReplaceAllString(filter, ":1")
+	filter = shardVersionMatched.ReplaceAllString(filter, "")
+	filter = constructorsMatched.ReplaceAllString(filter, "1")
+	filter = regexMatched.ReplaceAllString(filter, ": /regex/$2}")
+	return strings.Replace(strings.Replace(filter, "{ ", "{", -1), " }", "}", -1)
 }
 
 func printLogsSummary(arr []OpPerformanceDoc) string {
 	var buffer bytes.Buffer
+	shards := GetClusterShards(arr)
 	buffer.WriteString("\r+---------+--------+------+--------+------+---------------------------------+--------------------------------------------------------------+\n")
 	buffer.WriteString(fmt.Sprintf("| Command |COLLSCAN|avg ms| max ms | Count| %-32s| %-60s |\n", "Namespace", "Query Pattern"))
 	buffer.WriteString("|---------+--------+------+--------+------+---------------------------------+--------------------------------------------------------------|\n")
@@ -472,6 +710,22 @@ func printLogsSummary(arr []OpPerformanceDoc) string {
 			output = fmt.Sprintf("|...index: \x1b[32;1m%-128s\x1b[0m|\n", value.Index)
 			buffer.WriteString(output)
 		}
+		if value.KeysExamined+value.DocsExamined+value.NReturned+value.WriteConflicts > 0 {
+			if value.DocsExaminedPerReturned() >= highExaminedRatio {
+				output = fmt.Sprintf("|...stats: \x1b[31;1m%-128s\x1b[0m|\n", getMetricsSummary(value))
+			} else {
+				output = fmt.Sprintf("|...stats: %-128s|\n", getMetricsSummary(value))
+			}
+			buffer.WriteString(output)
+		}
+		if value.RoutedOps > 0 {
+			if value.IsBroadcast(shards) == true {
+				output = fmt.Sprintf("|..shards: \x1b[31;1m%-128s\x1b[0m|\n", getShardsSummary(value)+", BROADCAST")
+			} else {
+				output = fmt.Sprintf("|..shards: %-128s|\n", getShardsSummary(value))
+			}
+			buffer.WriteString(output)
+		}
 	}
 	buffer.WriteString("+---------+--------+------+--------+------+---------------------------------+--------------------------------------------------------------+\n")
 	return buffer.String()
@@ -0,0 +1,109 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+// number of lines sent to a worker at a time
+const logBatchSize = 1000
+
+// logAggregator aggregates slow ops of a worker
+type logAggregator struct {
+	batch     int // sequence of batches of a worker
+	examples  int
+	interval  int
+	opsMap    map[string]OpPerformanceDoc
+	plansMap  map[string]PlanFlipDoc // by query shapes
+	slowMilli int
+	slowOps   []SlowOps // heap
+	top       int
+	txn       TxnStatsDoc
+}
+
+func newLogAggregator(li *LogInfo) *logAggregator {
+	return &logAggregator{examples: li.examples, interval: li.interval, opsMap: map[string]OpPerformanceDoc{},
+		plansMap: map[string]PlanFlipDoc{}, slowMilli: li.slowMilli, top: li.top}
+}
+
+// add aggregates a slow op into its query pattern
+func (agg *logAggregator) add(rec logRecord) {
+	filter, err := GetQueryShape(rec.filter)
+	if err != nil { // e.g. truncated logs
+		filter = normalizeFilter(rec.filter)
+	}
+	key := getPatternKey(rec.op, rec.ns, filter, rec.scan)
+	milli := rec.milli
+	if milli >= agg.slowMilli {
+		agg.slowOps = pushSlowOps(agg.slowOps, agg.top, SlowOps{Milli: milli, Log: rec.line})
+	}
+	doc, ok := agg.opsMap[key]
+	if ok {
+		if isSlowerIndex(milli, rec.index, doc) {
+			doc.Index = rec.index
+		}
+		if milli > doc.MaxMilli {
+			doc.MaxMilli = milli
+		}
+		doc.TotalMilli += milli
+		doc.Count++
+	} else {
+		doc = OpPerformanceDoc{Command: rec.op, Namespace: rec.ns, Filter: filter, Hash: GetShapeHash(rec.op, filter),
+			TotalMilli: milli, MaxMilli: milli, Count: 1, Scan: rec.scan, Index: rec.index, Buckets: map[int64]OpBucketDoc{}}
+	}
+	doc.addMetrics(rec.opMetrics)
+	if rec.timestamp.IsZero() == false {
+		addToBucket(doc.Buckets, rec.timestamp, agg.interval, milli)
+	}
+	doc.Examples = pushSlowOps(doc.Examples, agg.examples, SlowOps{Milli: milli, Log: rec.line})
+	agg.opsMap[key] = doc
+	agg.addPlan(rec, filter)
+}
+
+// isSlowerIndex tells whether an index replaces the index of a pattern, the index of the slowest op is reported and
+// the lesser non-empty index of ties, so that results don't depend on the order of parsing
+func isSlowerIndex(milli int, index string, doc OpPerformanceDoc) bool {
+	return milli > doc.MaxMilli || (milli == doc.MaxMilli && index != "" && (doc.Index == "" || index < doc.Index))
+}
+
+// getPatternKey returns key of a query pattern
+func getPatternKey(op string, ns string, filter string, scan string) string {
+	return op + "." + ns + "." + filter + "." + scan
+}
+
+// merge merges results of another aggregator
+func (agg *logAggregator) merge(other *logAggregator) {
+	for _, op := range other.slowOps {
+		agg.slowOps = pushSlowOps(agg.slowOps, agg.top, op)
+	}
+	for key, value := range other.opsMap {
+		doc, ok := agg.opsMap[key]
+		if ok == false {
+			agg.opsMap[key] = value
+			continue
+		}
+		if isSlowerIndex(value.MaxMilli, value.Index, doc) {
+			doc.Index = value.Index
+		}
+		if value.MaxMilli > doc.MaxMilli {
+			doc.MaxMilli = value.MaxMilli
+		}
+		doc.TotalMilli += value.TotalMilli
+		doc.Count += value.Count
+		doc.mergeMetrics(value)
+		for _, op := range value.Examples {
+			doc.Examples = pushSlowOps(doc.Examples, agg.examples, op)
+		}
+		for t, bucket := range value.Buckets {
+			doc.Buckets[t] = mergeBucket(doc.Buckets[t], bucket)
+		}
+		if doc.Hosts == nil && len(value.Hosts) > 0 {
+			doc.Hosts = map[string]OpBucketDoc{}
+		}
+		for host, bucket := range value.Hosts {
+			doc.Hosts[host] = mergeBucket(doc.Hosts[host], bucket)
+		}
+		agg.opsMap[key] = doc
+	}
+	for key, value := range other.plansMap {
+		agg.mergePlans(key, value)
+	}
+	agg.mergeTxn(other.txn)
+}
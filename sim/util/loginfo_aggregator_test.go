@@ -0,0 +1,78 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestLogAggregatorMerge(t *testing.T) {
+	x := newLogAggregator(NewLogInfo("mongod.log"))
+	y := newLogAggregator(NewLogInfo("mongod.log"))
+	rec, _ := parseJSONLogLine(jsonLogLines[1])
+	x.add(rec)
+	rec, _ = parseJSONLogLine(jsonLogLines[2])
+	y.add(rec)
+	rec, _ = parseJSONLogLine(jsonLogLines[3])
+	y.add(rec)
+	x.merge(y)
+	if len(x.opsMap) != 2 || len(x.slowOps) != 1 {
+		t.Fatal(x.opsMap)
+	}
+	for _, doc := range x.opsMap {
+		if doc.Command == "find" && (doc.Count != 2 || doc.TotalMilli != 400 || doc.MaxMilli != 250 || len(doc.Buckets) != 1) {
+			t.Fatal(doc)
+		}
+	}
+}
+
+func TestLogAggregatorIndex(t *testing.T) {
+	recs := []logRecord{
+		{filter: "{a: 1}", index: "{ a: 1 }", milli: 200, ns: "db.c", op: "find", scan: "IXSCAN"},
+		{filter: "{a: 1}", index: "{ a: 1, b: 1 }", milli: 500, ns: "db.c", op: "find", scan: "IXSCAN"},
+		{filter: "{a: 1}", index: "{ a: -1 }", milli: 500, ns: "db.c", op: "find", scan: "IXSCAN"},
+		{filter: "{a: 1}", index: "", milli: 500, ns: "db.c", op: "find", scan: "IXSCAN"},
+	}
+	for _, order := range [][]int{{0, 1, 2, 3}, {2, 1, 0, 3}, {1, 0, 2, 3}, {3, 0, 1, 2}, {0, 3, 2, 1}} {
+		x := newLogAggregator(NewLogInfo("mongod.log"))
+		y := newLogAggregator(NewLogInfo("mongod.log"))
+		x.add(recs[order[0]])
+		x.add(recs[order[1]])
+		y.add(recs[order[2]])
+		y.add(recs[order[3]])
+		x.merge(y)
+		for _, doc := range x.opsMap {
+			if doc.Index != "{ a: -1 }" {
+				t.Fatal(order, doc.Index)
+			}
+		}
+	}
+}
+
+func TestParseGzipLogFile(t *testing.T) {
+	filename := "loginfo_aggregator_test.log.gz"
+	file, _ := os.Create(filename)
+	defer os.Remove(filename)
+	zw := gzip.NewWriter(file)
+	for i := 0; i < 5*logBatchSize+1; i++ {
+		fmt.Fprintf(zw, "2019-03-01T10:%02d:00.000+0000 I COMMAND  [conn%d] command keyhole.cars command: find { find: \"cars\", filter: { color: \"Red\", year: %d } } planSummary: COLLSCAN keysExamined:0 docsExamined:100 nreturned:1 reslen:200 %dms\n",
+			i%60, i, i, 100+i%10)
+	}
+	zw.Close()
+	file.Close()
+
+	li := NewLogInfo(filename)
+	li.SetSilent(true)
+	if err := li.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if li.OutputFilename != "loginfo_aggregator_test.log.enc" || len(li.OpsPatterns) != 1 {
+		t.Fatal(li.OutputFilename, li.OpsPatterns)
+	}
+	if doc := li.OpsPatterns[0]; doc.Count != 5*logBatchSize+1 || doc.MaxMilli != 109 || doc.Scan != COLLSCAN || len(doc.Buckets) != 60 {
+		t.Fatal(doc.Count, doc.MaxMilli, doc.Scan, len(doc.Buckets))
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpBucketDoc stores performance data of a time interval
+type OpBucketDoc struct {
+	Count      int
+	MaxMilli   int
+	TotalMilli int
+}
+
+// addToBucket adds an op to the bucket of its interval
+func addToBucket(buckets map[int64]OpBucketDoc, tm time.Time, interval int, milli int) {
+	t := tm.Unix() - tm.Unix()%int64(interval)
+	bucket := buckets[t]
+	bucket.Count++
+	bucket.TotalMilli += milli
+	if milli > bucket.MaxMilli {
+		bucket.MaxMilli = milli
+	}
+	buckets[t] = bucket
+}
+
+// mergeBucket returns sums of two buckets
+func mergeBucket(a OpBucketDoc, b OpBucketDoc) OpBucketDoc {
+	a.Count += b.Count
+	a.TotalMilli += b.TotalMilli
+	if b.MaxMilli > a.MaxMilli {
+		a.MaxMilli = b.MaxMilli
+	}
+	return a
+}
+
+// GetBucketTimes returns sorted epoch seconds of buckets
+func GetBucketTimes(buckets map[int64]OpBucketDoc) []int64 {
+	times := make([]int64, 0, len(buckets))
+	for t := range buckets {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times
+}
+
+// GetHistogram returns text histograms of ops by time interval of top n patterns by total milliseconds
+func (li *LogInfo) GetHistogram(n int) string {
+	patterns := append([]OpPerformanceDoc{}, li.OpsPatterns...)
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].TotalMilli > patterns[j].TotalMilli })
+	if len(patterns) > n {
+		patterns = patterns[:n]
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("Ops by %d-second interval, top %d patterns by total time:\n", li.interval, len(patterns)))
+	for _, doc := range patterns {
+		if len(doc.Buckets) == 0 {
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("\n%s %s %s\n", doc.Command, doc.Namespace, doc.Filter))
+		buffer.WriteString(fmt.Sprintf("%-20s %6s %6s %8s\n", "Time (UTC)", "Count", "avg ms", "max ms"))
+		max := 0
+		for _, bucket := range doc.Buckets {
+			if bucket.Count > max {
+				max = bucket.Count
+			}
+		}
+		for _, t := range GetBucketTimes(doc.Buckets) {
+			bucket := doc.Buckets[t]
+			bar := strings.Repeat("#", (50*bucket.Count+max-1)/max)
+			buffer.WriteString(fmt.Sprintf("%-20s %6d %6s %8d %s\n", time.Unix(t, 0).UTC().Format("2006-01-02T15:04:05"),
+				bucket.Count, MilliToTimeString(float64(bucket.TotalMilli)/float64(bucket.Count)), bucket.MaxMilli, bar))
+		}
+	}
+	return buffer.String()
+}
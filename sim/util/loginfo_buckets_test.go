@@ -0,0 +1,44 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAddToBucket(t *testing.T) {
+	buckets := map[int64]OpBucketDoc{}
+	tm := time.Date(2019, 3, 1, 12, 0, 10, 0, time.UTC)
+	addToBucket(buckets, tm, 60, 100)
+	addToBucket(buckets, tm.Add(30*time.Second), 60, 300)
+	addToBucket(buckets, tm.Add(time.Minute), 60, 200)
+	times := GetBucketTimes(buckets)
+	if len(times) != 2 || times[0] != tm.Unix()-10 {
+		t.Fatal(times)
+	}
+	if b := buckets[times[0]]; b.Count != 2 || b.TotalMilli != 400 || b.MaxMilli != 300 {
+		t.Fatal(b)
+	}
+}
+
+func TestGetHistogram(t *testing.T) {
+	li := NewLogInfo("mongod.log")
+	li.SetInterval(60)
+	agg := newLogAggregator(NewLogInfo("mongod.log"))
+	for _, line := range jsonLogLines {
+		li.parseLogLine(agg, line, true)
+	}
+	li.parseLogLine(agg, `2020-08-10T12:01:05.000Z I COMMAND  [conn5] command keyhole.cars command: find { find: "cars", filter: { color: "Red" } } planSummary: IXSCAN { color: 1 } keysExamined:10 docsExamined:10 nreturned:10 reslen:300 300ms`, false)
+	for _, doc := range agg.opsMap {
+		li.OpsPatterns = append(li.OpsPatterns, doc)
+	}
+	str := li.GetHistogram(10)
+	if regexp.MustCompile(`2020-08-10T12:00:00\s+2\s+200\s+250 #+`).MatchString(str) == false {
+		t.Fatal(str)
+	}
+	if regexp.MustCompile(`2020-08-10T12:01:00\s+1\s+300\s+300 #+`).MatchString(str) == false {
+		t.Fatal(str)
+	}
+}
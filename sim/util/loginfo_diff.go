@@ -0,0 +1,145 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// status of a query pattern between two analyses
+const (
+	PatternChanged = "CHANGED"
+	PatternGone    = "GONE"
+	PatternNew     = "NEW"
+)
+
+// PatternDiffDoc compares a query pattern, joined by shape hash and namespace, of two analyses
+type PatternDiffDoc struct {
+	After      OpPerformanceDoc
+	Before     OpPerformanceDoc
+	Command    string
+	Filter     string
+	Hash       string
+	Namespace  string
+	Status     string
+	ToCollscan bool // from index scans to COLLSCAN
+}
+
+// AvgMilliDelta returns the change of average milliseconds
+func (d PatternDiffDoc) AvgMilliDelta() float64 {
+	return avgMilli(d.After) - avgMilli(d.Before)
+}
+
+func avgMilli(doc OpPerformanceDoc) float64 {
+	if doc.Count == 0 {
+		return 0
+	}
+	return float64(doc.TotalMilli) / float64(doc.Count)
+}
+
+// patternsByShape sums patterns of the same shape and namespace of different plans
+func patternsByShape(patterns []OpPerformanceDoc) (map[string]OpPerformanceDoc, map[string]map[string]bool) {
+	docs := map[string]OpPerformanceDoc{}
+	plans := map[string]map[string]bool{}
+	for _, value := range patterns {
+		key := value.Hash + "." + value.Namespace
+		doc, ok := docs[key]
+		if ok == false {
+			doc = OpPerformanceDoc{Command: value.Command, Namespace: value.Namespace, Filter: value.Filter, Hash: value.Hash}
+			plans[key] = map[string]bool{}
+		}
+		doc.Count += value.Count
+		doc.TotalMilli += value.TotalMilli
+		if value.MaxMilli > doc.MaxMilli {
+			doc.MaxMilli = value.MaxMilli
+		}
+		if value.Scan == COLLSCAN {
+			plans[key][COLLSCAN] = true
+		} else {
+			plans[key]["IXSCAN"] = true
+		}
+		docs[key] = doc
+	}
+	return docs, plans
+}
+
+// DiffLogInfo compares query patterns of two analyses, regressions first
+func DiffLogInfo(before *LogInfo, after *LogInfo) []PatternDiffDoc {
+	bdocs, bplans := patternsByShape(before.OpsPatterns)
+	adocs, aplans := patternsByShape(after.OpsPatterns)
+	diffs := []PatternDiffDoc{}
+	for key, adoc := range adocs {
+		d := PatternDiffDoc{Command: adoc.Command, Namespace: adoc.Namespace, Filter: adoc.Filter, Hash: adoc.Hash, After: adoc}
+		if bdoc, ok := bdocs[key]; ok {
+			d.Before = bdoc
+			d.ToCollscan = bplans[key][COLLSCAN] == false && aplans[key][COLLSCAN] == true
+			if d.ToCollscan == true {
+				d.Status = PatternChanged
+			}
+		} else {
+			d.Status = PatternNew
+		}
+		diffs = append(diffs, d)
+	}
+	for key, bdoc := range bdocs {
+		if _, ok := adocs[key]; ok == false {
+			diffs = append(diffs, PatternDiffDoc{Command: bdoc.Command, Namespace: bdoc.Namespace, Filter: bdoc.Filter,
+				Hash: bdoc.Hash, Before: bdoc, Status: PatternGone})
+		}
+	}
+	rank := func(d PatternDiffDoc) int {
+		switch d.Status {
+		case PatternChanged:
+			return 0
+		case PatternNew:
+			return 1
+		case PatternGone:
+			return 3
+		}
+		return 2
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if rank(diffs[i]) != rank(diffs[j]) {
+			return rank(diffs[i]) < rank(diffs[j])
+		}
+		return diffs[i].AvgMilliDelta() > diffs[j].AvgMilliDelta()
+	})
+	return diffs
+}
+
+// PrintLogInfoDiff prints comparisons of query patterns
+func PrintLogInfoDiff(diffs []PatternDiffDoc) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("+---------+---------+-----------------+-----------------+-----------------+---------------------------------+\n")
+	buffer.WriteString(fmt.Sprintf("| %-7s | Command |  Count (before) |  avg ms (delta) |  max ms (delta) | %-32s|\n", "Status", "Namespace"))
+	buffer.WriteString("|---------+---------+-----------------+-----------------+-----------------+---------------------------------|\n")
+	for _, d := range diffs {
+		command := d.Command
+		if len(command) > 7 {
+			command = command[:7]
+		}
+		ns := d.Namespace
+		if len(ns) > 32 {
+			ns = ns[:1] + "*" + ns[len(ns)-30:]
+		}
+		count := fmt.Sprintf("%d (%d)", d.After.Count, d.Before.Count)
+		avg := fmt.Sprintf("%s (%+.0f)", MilliToTimeString(avgMilli(d.After)), d.AvgMilliDelta())
+		max := fmt.Sprintf("%d (%+d)", d.After.MaxMilli, d.After.MaxMilli-d.Before.MaxMilli)
+		status := d.Status
+		if status != "" {
+			status = "\x1b[31;1m" + fmt.Sprintf("%-7s", status) + "\x1b[0m"
+		} else {
+			status = fmt.Sprintf("%-7s", status)
+		}
+		buffer.WriteString(fmt.Sprintf("| %s | %-7s | %15s | %15s | %15s | %-32s|\n", status, command, count, avg, max, ns))
+		pattern := d.Filter
+		if d.ToCollscan == true {
+			pattern += " (IXSCAN => COLLSCAN)"
+		}
+		buffer.WriteString(fmt.Sprintf("|...pattern: %-95s|\n", pattern))
+	}
+	buffer.WriteString("+---------+---------+-----------------+-----------------+-----------------+---------------------------------+\n")
+	return buffer.String()
+}
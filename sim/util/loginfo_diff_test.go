@@ -0,0 +1,68 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiffLogInfo(t *testing.T) {
+	before := &LogInfo{OpsPatterns: []OpPerformanceDoc{
+		OpPerformanceDoc{Command: "find", Namespace: "db.a", Filter: "{a: <number>}", Hash: "h1", Count: 10, TotalMilli: 1000, MaxMilli: 200, Index: "{ a: 1 }"},
+		OpPerformanceDoc{Command: "find", Namespace: "db.b", Filter: "{b: <number>}", Hash: "h2", Count: 10, TotalMilli: 1000, MaxMilli: 200, Index: "{ b: 1 }"},
+		OpPerformanceDoc{Command: "count", Namespace: "db.c", Filter: "{c: <number>}", Hash: "h3", Count: 1, TotalMilli: 100, MaxMilli: 100},
+	}}
+	after := &LogInfo{OpsPatterns: []OpPerformanceDoc{
+		OpPerformanceDoc{Command: "find", Namespace: "db.a", Filter: "{a: <number>}", Hash: "h1", Count: 20, TotalMilli: 4000, MaxMilli: 500, Index: "{ a: 1 }"},
+		OpPerformanceDoc{Command: "find", Namespace: "db.b", Filter: "{b: <number>}", Hash: "h2", Count: 5, TotalMilli: 2500, MaxMilli: 900, Scan: COLLSCAN},
+		OpPerformanceDoc{Command: "find", Namespace: "db.b", Filter: "{b: <number>}", Hash: "h2", Count: 5, TotalMilli: 500, MaxMilli: 100, Index: "{ b: 1 }"},
+		OpPerformanceDoc{Command: "find", Namespace: "db.d", Filter: "{d: <number>}", Hash: "h4", Count: 1, TotalMilli: 300, MaxMilli: 300},
+	}}
+	diffs := DiffLogInfo(before, after)
+	if len(diffs) != 4 {
+		t.Fatal(diffs)
+	}
+	if d := diffs[0]; d.Hash != "h2" || d.Status != PatternChanged || d.ToCollscan == false || d.After.Count != 10 || d.AvgMilliDelta() != 200 {
+		t.Fatal(d)
+	}
+	if d := diffs[1]; d.Hash != "h4" || d.Status != PatternNew {
+		t.Fatal(d)
+	}
+	if d := diffs[2]; d.Hash != "h1" || d.Status != "" || d.AvgMilliDelta() != 100 {
+		t.Fatal(d)
+	}
+	if d := diffs[3]; d.Hash != "h3" || d.Status != PatternGone {
+		t.Fatal(d)
+	}
+	if str := PrintLogInfoDiff(diffs); strings.Index(str, "IXSCAN => COLLSCAN") < 0 {
+		t.Fatal(str)
+	}
+}
+
+func TestLoadEncoded(t *testing.T) {
+	filename := "loginfo_diff_test.enc"
+	var data bytes.Buffer
+	gob.NewEncoder(&data).Encode(&LogInfo{OpsPatterns: []OpPerformanceDoc{OpPerformanceDoc{Command: "find", Count: 2}}, Version: logInfoVersion})
+	ioutil.WriteFile(filename, data.Bytes(), 0644)
+	defer os.Remove(filename)
+	li := NewLogInfo(filename)
+	if err := li.Load(); err != nil || len(li.OpsPatterns) != 1 || li.OpsPatterns[0].Count != 2 {
+		t.Fatal(err, li.OpsPatterns)
+	}
+}
+
+func TestLoadStaleEncoded(t *testing.T) {
+	filename := "loginfo_diff_test_stale.enc"
+	var data bytes.Buffer
+	gob.NewEncoder(&data).Encode(&LogInfo{OpsPatterns: []OpPerformanceDoc{OpPerformanceDoc{Command: "find", Count: 2}}, Version: 1})
+	ioutil.WriteFile(filename, data.Bytes(), 0644)
+	defer os.Remove(filename)
+	if err := NewLogInfo(filename).Load(); err == nil {
+		t.Fatal("expected an error of a stale version")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// version of encoded outputs (.enc), changes when LogInfo is incompatible
+const logInfoVersion = 5 // 5: transactions and write conflicts
+
+// MergeLogInfo merges results of members into a cluster wide view, ops are broken down by hosts
+func MergeLogInfo(infos []*LogInfo) *LogInfo {
+	merged := *infos[0]
+	merged.OutputFilename = "loginfo-merged.enc"
+	hosts := []string{}
+	agg := newLogAggregator(&merged)
+	for _, li := range infos {
+		hosts = append(hosts, li.Host)
+		agg.merge(li.getAggregator())
+	}
+	merged.Host = strings.Join(hosts, ", ")
+	merged.setResults(agg)
+	return &merged
+}
+
+// getAggregator returns a copy of results as an aggregator, log lines are prefixed with host
+func (li *LogInfo) getAggregator() *logAggregator {
+	agg := newLogAggregator(li)
+	prefix := "[" + li.Host + "] "
+	for _, op := range li.SlowOps {
+		if strings.HasPrefix(op.Log, "[") == false {
+			op.Log = prefix + op.Log
+		}
+		agg.slowOps = append(agg.slowOps, op)
+	}
+	for _, value := range li.OpsPatterns {
+		doc := value
+		doc.Buckets = map[int64]OpBucketDoc{}
+		for t, bucket := range value.Buckets {
+			doc.Buckets[t] = bucket
+		}
+		doc.Hosts = map[string]OpBucketDoc{}
+		for host, bucket := range value.Hosts {
+			doc.Hosts[host] = bucket
+		}
+		if len(doc.Hosts) == 0 {
+			doc.Hosts[li.Host] = OpBucketDoc{Count: value.Count, MaxMilli: value.MaxMilli, TotalMilli: value.TotalMilli}
+		}
+		doc.Examples = []SlowOps{}
+		for _, op := range value.Examples {
+			if strings.HasPrefix(op.Log, "[") == false {
+				op.Log = prefix + op.Log
+			}
+			doc.Examples = append(doc.Examples, op)
+		}
+		agg.opsMap[getPatternKey(doc.Command, doc.Namespace, doc.Filter, doc.Scan)] = doc
+	}
+	agg.mergeTxn(li.Transactions)
+	for _, value := range li.PlanFlips { // plans of members are not interleaved
+		doc := value
+		if doc.Host == "" {
+			doc.Host = li.Host
+		}
+		doc.Plans = map[string]OpBucketDoc{}
+		for plan, bucket := range value.Plans {
+			doc.Plans[plan] = bucket
+		}
+		doc.Runs = append([]PlanRunDoc{}, value.Runs...)
+		agg.plansMap[doc.Host+" "+getPatternKey(doc.Command, doc.Namespace, doc.Filter, "")] = doc
+	}
+	return agg
+}
+
+// GetHostsBreakdown returns ops of query patterns by hosts
+func (li *LogInfo) GetHostsBreakdown() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("Query patterns by hosts:\n")
+	for _, doc := range li.OpsPatterns {
+		buffer.WriteString(fmt.Sprintf("\n%s %s %s\n", doc.Command, doc.Namespace, doc.Filter))
+		for _, host := range getSortedHosts(doc.Hosts) {
+			b := doc.Hosts[host]
+			buffer.WriteString(fmt.Sprintf("  %-40s count: %6d, avg ms: %6s, max ms: %8d\n", host, b.Count,
+				strings.TrimSpace(MilliToTimeString(float64(b.TotalMilli)/float64(b.Count))), b.MaxMilli))
+		}
+	}
+	return buffer.String()
+}
+
+func getSortedHosts(hosts map[string]OpBucketDoc) []string {
+	keys := make([]string, 0, len(hosts))
+	for host := range hosts {
+		keys = append(keys, host)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetJSON returns query patterns and slowest ops in JSON
+func (li *LogInfo) GetJSON() (string, error) {
+	doc := struct {
+		Host         string             `json:"host"`
+		OpsPatterns  []OpPerformanceDoc `json:"opsPatterns"`
+		PlanFlips    []PlanFlipDoc      `json:"planFlips"`
+		SlowOps      []SlowOps          `json:"slowOps"`
+		Transactions TxnStatsDoc        `json:"transactions"`
+		Version      int                `json:"version"`
+	}{Host: li.Host, OpsPatterns: li.OpsPatterns, PlanFlips: li.PlanFlips, SlowOps: li.SlowOps, Transactions: li.Transactions,
+		Version: logInfoVersion}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	return string(data), err
+}
+
+// WriteCSV writes query patterns and slowest ops to CSV files, a row of a pattern has totals and execution metrics,
+// followed by rows of hosts of merged results with their counts and durations
+func (li *LogInfo) WriteCSV() ([]string, error) {
+	var err error
+	prefix := strings.TrimSuffix(li.OutputFilename, ".enc")
+	rows := [][]string{{"host", "command", "ns", "scan", "index", "hash", "count", "avgMilli", "maxMilli", "totalMilli",
+		"keysExamined", "docsExamined", "nreturned", "docsExaminedPerReturned", "sortStages", "avgShards", "maxShards", "txnOps", "writeConflicts", "filter"}}
+	for _, doc := range li.OpsPatterns {
+		rows = append(rows, []string{li.Host, doc.Command, doc.Namespace, doc.Scan, doc.Index, doc.Hash, strconv.Itoa(doc.Count),
+			strconv.FormatFloat(float64(doc.TotalMilli)/float64(doc.Count), 'f', 1, 64), strconv.Itoa(doc.MaxMilli),
+			strconv.Itoa(doc.TotalMilli), strconv.Itoa(doc.KeysExamined), strconv.Itoa(doc.DocsExamined), strconv.Itoa(doc.NReturned),
+			strconv.FormatFloat(doc.DocsExaminedPerReturned(), 'f', 1, 64), strconv.Itoa(doc.SortStages),
+			strconv.FormatFloat(doc.AvgShards(), 'f', 1, 64), strconv.Itoa(doc.MaxShards),
+			strconv.Itoa(doc.TxnOps), strconv.Itoa(doc.WriteConflicts), doc.Filter})
+		for _, host := range getSortedHosts(doc.Hosts) { // metrics are of patterns, not kept by hosts
+			b := doc.Hosts[host]
+			rows = append(rows, []string{host, doc.Command, doc.Namespace, doc.Scan, doc.Index, doc.Hash, strconv.Itoa(b.Count),
+				strconv.FormatFloat(float64(b.TotalMilli)/float64(b.Count), 'f', 1, 64), strconv.Itoa(b.MaxMilli),
+				strconv.Itoa(b.TotalMilli), "", "", "", "", "", "", "", "", "", doc.Filter})
+		}
+	}
+	filenames := []string{prefix + "-patterns.csv", prefix + "-slowops.csv"}
+	if err = writeCSVFile(filenames[0], rows); err != nil {
+		return nil, err
+	}
+	rows = [][]string{{"milli", "log"}}
+	for _, op := range li.SlowOps {
+		rows = append(rows, []string{strconv.Itoa(op.Milli), op.Log})
+	}
+	if err = writeCSVFile(filenames[1], rows); err != nil {
+		return nil, err
+	}
+	return filenames, nil
+}
+
+func writeCSVFile(filename string, rows [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	w.WriteAll(rows)
+	return w.Error()
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func getTestLogInfo(host string) *LogInfo {
+	li := NewLogInfo(host + ".log")
+	li.SetSlowMilli(0)
+	li.SetExamples(1)
+	agg := newLogAggregator(li)
+	for _, line := range jsonLogLines {
+		li.parseLogLine(agg, line, true)
+	}
+	li.Host = host
+	li.setResults(agg)
+	return li
+}
+
+func TestMergeLogInfo(t *testing.T) {
+	x := getTestLogInfo("host1:27017")
+	y := getTestLogInfo("host2:27017")
+	merged := MergeLogInfo([]*LogInfo{x, y})
+	if merged.Host != "host1:27017, host2:27017" || len(merged.OpsPatterns) != len(x.OpsPatterns) {
+		t.Fatal(merged.Host, len(merged.OpsPatterns))
+	}
+	for i, doc := range merged.OpsPatterns {
+		if doc.Count != 2*x.OpsPatterns[i].Count || len(doc.Hosts) != 2 || doc.Hosts["host2:27017"].Count != x.OpsPatterns[i].Count {
+			t.Fatal(doc)
+		}
+		if strings.HasPrefix(doc.Examples[0].Log, "[host") == false {
+			t.Fatal(doc.Examples[0].Log)
+		}
+	}
+	if len(x.OpsPatterns[0].Hosts) != 0 {
+		t.Fatal("merged inputs", x.OpsPatterns[0].Hosts)
+	}
+	if str := merged.GetSummary(); strings.Index(str, "Query patterns by hosts:") < 0 {
+		t.Fatal(str)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	li := getTestLogInfo("host1:27017")
+	li.OutputFilename = "loginfo_export_test.enc"
+	defer os.Remove(li.OutputFilename)
+	if err := li.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewLogInfo(li.OutputFilename)
+	if err := loaded.Load(); err != nil || loaded.Host != li.Host || len(loaded.OpsPatterns) != len(li.OpsPatterns) {
+		t.Fatal(err, loaded.Host)
+	}
+
+	var data bytes.Buffer
+	gob.NewEncoder(&data).Encode(&LogInfo{OpsPatterns: li.OpsPatterns}) // without version
+	ioutil.WriteFile(li.OutputFilename, data.Bytes(), 0644)
+	if err := NewLogInfo(li.OutputFilename).Load(); err == nil {
+		t.Fatal("expected unsupported version error")
+	}
+}
+
+func TestGetJSON(t *testing.T) {
+	li := getTestLogInfo("host1:27017")
+	str, err := li.GetJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Host        string             `json:"host"`
+		OpsPatterns []OpPerformanceDoc `json:"opsPatterns"`
+		Version     int                `json:"version"`
+	}
+	if err = json.Unmarshal([]byte(str), &doc); err != nil || doc.Host != li.Host || len(doc.OpsPatterns) != len(li.OpsPatterns) || doc.Version != logInfoVersion {
+		t.Fatal(err, str)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	li := MergeLogInfo([]*LogInfo{getTestLogInfo("host1:27017"), getTestLogInfo("host2:27017")})
+	filenames, err := li.WriteCSV()
+	for _, filename := range filenames {
+		defer os.Remove(filename)
+	}
+	if err != nil || len(filenames) != 2 {
+		t.Fatal(err, filenames)
+	}
+	data, _ := ioutil.ReadFile(filenames[0])
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1+3*len(li.OpsPatterns) || strings.HasPrefix(lines[1], `"host1:27017, host2:27017",`) == false ||
+		strings.HasPrefix(lines[2], "host1:27017,") == false || strings.HasPrefix(lines[3], "host2:27017,") == false {
+		t.Fatal(string(data))
+	}
+	doc := li.OpsPatterns[0]
+	if strings.Index(lines[1], fmt.Sprintf(",%d,%d,%d,", doc.KeysExamined, doc.DocsExamined, doc.NReturned)) < 0 ||
+		strings.Index(lines[2], fmt.Sprintf(",%d,,,,,,,,,,", doc.TotalMilli/2)) < 0 {
+		t.Fatal(lines[1], lines[2])
+	}
+	single := getTestLogInfo("host1:27017")
+	if filenames, err = single.WriteCSV(); err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range filenames {
+		defer os.Remove(filename)
+	}
+	data, _ = ioutil.ReadFile(filenames[0])
+	if lines = strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 1+len(single.OpsPatterns) {
+		t.Fatal(string(data))
+	}
+}
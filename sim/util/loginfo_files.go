@@ -0,0 +1,138 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// number of leading lines read to find the first timestamp of a log file
+const logTimeLines = 100
+
+// GetLogFilenames returns log files of a file, a glob, e.g. mongod.log*, or a directory, ordered by their first
+// timestamps, files without timestamps last
+func GetLogFilenames(pattern string) ([]string, error) {
+	var err error
+	var filenames []string
+	if fi, serr := os.Stat(pattern); serr == nil && fi.IsDir() {
+		var infos []os.FileInfo
+		if infos, err = ioutil.ReadDir(pattern); err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if info.Mode().IsRegular() && strings.HasPrefix(info.Name(), ".") == false &&
+				strings.HasSuffix(info.Name(), ".enc") == false {
+				filenames = append(filenames, filepath.Join(pattern, info.Name()))
+			}
+		}
+	} else if strings.ContainsAny(pattern, "*?[") {
+		var matches []string
+		if matches, err = filepath.Glob(pattern); err != nil {
+			return nil, err
+		}
+		for _, filename := range matches {
+			if strings.HasSuffix(filename, ".enc") == false { // encoded outputs
+				filenames = append(filenames, filename)
+			}
+		}
+	} else {
+		return []string{pattern}, nil
+	}
+	if len(filenames) == 0 {
+		return nil, errors.New("no log files found in " + pattern)
+	}
+	times := map[string]time.Time{}
+	for _, filename := range filenames {
+		times[filename] = getLogFileTime(filename)
+	}
+	sort.SliceStable(filenames, func(i, j int) bool {
+		a, b := times[filenames[i]], times[filenames[j]]
+		if a.IsZero() || b.IsZero() {
+			return b.IsZero() && (a.IsZero() == false || filenames[i] < filenames[j])
+		}
+		return a.Before(b)
+	})
+	return filenames, nil
+}
+
+// GetRotatedLogPattern returns a glob, e.g. mongod.log*, of rotated logs of a member expanded by a shell from an
+// unquoted glob, or an empty string if files are logs of different members
+func GetRotatedLogPattern(filenames []string) string {
+	logs := map[string]bool{}
+	base := ""
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, ".enc") { // encoded outputs also matched by the glob
+			continue
+		}
+		logs[filepath.Clean(filename)] = true
+		if base == "" || len(filename) < len(base) {
+			base = filepath.Clean(filename)
+		}
+	}
+	if len(logs) < 2 {
+		return ""
+	}
+	for filename := range logs {
+		if strings.HasPrefix(filename, base) == false {
+			return ""
+		}
+	}
+	matches, err := GetLogFilenames(base + "*")
+	if err != nil || len(matches) != len(logs) {
+		return ""
+	}
+	for _, filename := range matches {
+		if logs[filepath.Clean(filename)] == false {
+			return ""
+		}
+	}
+	return base + "*"
+}
+
+// getLogFileTime returns the first timestamp of a plain or gzip log file
+func getLogFileTime(filename string) time.Time {
+	file, err := os.Open(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	defer file.Close()
+	reader, _, err := NewCountingReader(file)
+	if err != nil {
+		return time.Time{}
+	}
+	for i := 0; i < logTimeLines; i++ {
+		str, err := reader.ReadString('\n')
+		if t := getLogLineTime(str); t.IsZero() == false {
+			return t
+		} else if err != nil {
+			break
+		}
+	}
+	return time.Time{}
+}
+
+// getLogLineTime returns timestamp of a legacy or a structured log line
+func getLogLineTime(str string) time.Time {
+	if strings.HasPrefix(str, "{") {
+		var doc struct {
+			T struct {
+				Date string `json:"$date"`
+			} `json:"t"`
+		}
+		json.Unmarshal([]byte(str), &doc)
+		t, _ := time.Parse(time.RFC3339, doc.T.Date)
+		return t
+	}
+	t := time.Time{}
+	if i := strings.Index(str, " "); i > 0 {
+		t, _ = time.Parse(legacyTimeLayout, str[:i])
+	}
+	return t
+}
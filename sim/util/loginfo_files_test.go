@@ -0,0 +1,107 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRotatedLogs writes logs of 3 hours, the oldest gzipped and the latest in JSON
+func writeRotatedLogs(dir string) error {
+	line := "2019-03-01T%02d:00:00.000+0000 I COMMAND  [conn1] command keyhole.cars command: find { find: \"cars\", filter: { color: \"Red\" } } planSummary: COLLSCAN keysExamined:0 docsExamined:100 nreturned:1 reslen:200 100ms\n"
+	file, err := os.Create(filepath.Join(dir, "mongod.log.2019-03-01T10-00-00.gz"))
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(file)
+	fmt.Fprintf(zw, line, 9)
+	zw.Close()
+	file.Close()
+	if err = ioutil.WriteFile(filepath.Join(dir, "mongod.log.1"), []byte(fmt.Sprintf(line, 10)), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "mongod.log"), []byte(`{"t":{"$date":"2019-03-01T11:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"keyhole.cars","command":{"find":"cars","filter":{"color":"Blue"},"$db":"keyhole"},"planSummary":"COLLSCAN","durationMillis":300}}`+"\n"), 0644)
+}
+
+func TestGetLogFilenames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyhole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = writeRotatedLogs(dir); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "mongod.log.enc"), []byte{}, 0644)
+	expected := []string{"mongod.log.2019-03-01T10-00-00.gz", "mongod.log.1", "mongod.log"}
+	for _, pattern := range []string{dir, filepath.Join(dir, "mongod.log*")} {
+		filenames, err := GetLogFilenames(pattern)
+		if err != nil || len(filenames) != len(expected) {
+			t.Fatal(err, filenames)
+		}
+		for i, filename := range filenames {
+			if filepath.Base(filename) != expected[i] {
+				t.Fatal(filenames)
+			}
+		}
+	}
+	if _, err = GetLogFilenames(filepath.Join(dir, "*.none")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGetRotatedLogPattern(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyhole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = writeRotatedLogs(dir); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "mongod.log.enc"), []byte{}, 0644)
+	expanded, _ := filepath.Glob(filepath.Join(dir, "mongod.log*")) // by a shell
+	if pattern := GetRotatedLogPattern(expanded); pattern != filepath.Join(dir, "mongod.log*") {
+		t.Fatal(pattern, expanded)
+	}
+	members := []string{filepath.Join(dir, "mongod.log"), filepath.Join(dir, "mongod.log.1")} // a rotated log left out
+	if pattern := GetRotatedLogPattern(members); pattern != "" {
+		t.Fatal(pattern)
+	}
+	members = []string{"rs1/mongod.log", "rs2/mongod.log"}
+	if pattern := GetRotatedLogPattern(members); pattern != "" {
+		t.Fatal(pattern)
+	}
+}
+
+func TestParseRotatedLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyhole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = writeRotatedLogs(dir); err != nil {
+		t.Fatal(err)
+	}
+	li := NewLogInfo(filepath.Join(dir, "mongod.log*"))
+	li.SetSilent(true)
+	li.SetInterval(3600)
+	if err = li.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if li.OutputFilename != "mongod.log.enc" || len(li.OpsPatterns) != 1 {
+		t.Fatal(li.OutputFilename, li.OpsPatterns)
+	}
+	if doc := li.OpsPatterns[0]; doc.Count != 3 || doc.TotalMilli != 500 || len(doc.Buckets) != 3 {
+		t.Fatal(doc)
+	}
+	if li = NewLogInfo(dir + "/"); strings.HasSuffix(li.OutputFilename, ".enc") == false {
+		t.Fatal(li.OutputFilename)
+	}
+}
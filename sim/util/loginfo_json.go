@@ -0,0 +1,230 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// appendJSONConfigOptions appends version and options of a structured log (4.4+), returns true once options are found
+func appendJSONConfigOptions(strs []string, buf []byte) ([]string, bool) {
+	var doc struct {
+		Msg  string `json:"msg"`
+		Attr struct {
+			BuildInfo struct {
+				Version string `json:"version"`
+			} `json:"buildInfo"`
+			Options json.RawMessage `json:"options"`
+		} `json:"attr"`
+	}
+	if json.Unmarshal(buf, &doc) != nil {
+		return strs, false
+	}
+	if doc.Msg == "Build Info" && doc.Attr.BuildInfo.Version != "" {
+		strs = append(strs, "db version v"+doc.Attr.BuildInfo.Version)
+	} else if doc.Msg == "Options set by command line" && len(doc.Attr.Options) > 0 {
+		var buffer bytes.Buffer
+		json.Indent(&buffer, doc.Attr.Options, "", "  ")
+		strs = append(strs, "config options:")
+		strs = append(strs, buffer.String())
+		return strs, true
+	}
+	return strs, false
+}
+
+// parseJSONLogLine parses a slow op of the structured log format (4.4+), e.g.
+// {"t":{"$date":"..."},"s":"I","c":"COMMAND","msg":"Slow query","attr":{"type":"command","ns":"db.coll","command":{...},...}}
+func parseJSONLogLine(str string) (logRecord, bool) {
+	rec := logRecord{line: str}
+	if strings.Index(str, `"Slow query"`) < 0 {
+		return rec, false
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+		return rec, false
+	}
+	if msg, _ := getDocValue(doc, "msg").(string); msg != "Slow query" {
+		return rec, false
+	}
+	if t, ok := getDocValue(doc, "t").(primitive.DateTime); ok {
+		rec.timestamp = time.Unix(0, int64(t)*int64(time.Millisecond))
+	}
+	return parseSlowOpAttr(rec, getDocValue(doc, "attr"))
+}
+
+// parseSlowOpAttr parses attributes of a slow op of a structured log, or a system.profile document
+func parseSlowOpAttr(rec logRecord, attr interface{}) (logRecord, bool) {
+	rec.ns, _ = getDocValue(attr, "ns").(string)
+	if rec.ns == "" || rec.ns == "local.oplog.rs" || strings.HasSuffix(rec.ns, ".$cmd") == true {
+		return rec, false
+	}
+	planSummary, _ := getDocValue(attr, "planSummary").(string)
+	if strings.HasPrefix(planSummary, COLLSCAN) {
+		rec.scan = COLLSCAN
+	}
+	rec.index = getIndexFromPlanSummary("planSummary: "+planSummary, rec.scan)
+	rec.milli = toInt(getDocValue(attr, "durationMillis"))
+	rec.opMetrics = getJSONMetrics(attr)
+
+	command := getDocValue(attr, "command")
+	opType, _ := getDocValue(attr, "type").(string)
+	if opType != "command" { // WRITE component, e.g. update and remove
+		rec.op = opType
+		if hasFilter(rec.op) == false {
+			return rec, false
+		}
+		rec.filter = ToShellString(getDocValue(command, "q"))
+		return rec, true
+	}
+	if cmd, ok := command.(bson.D); ok && len(cmd) > 0 {
+		rec.op = cmd[0].Key
+	} else {
+		return rec, false
+	}
+	if hasFilter(rec.op) == false {
+		return rec, false
+	}
+	switch rec.op {
+	case "find":
+		rec.filter = "{}"
+		if filter := getDocValue(command, "filter"); filter != nil {
+			rec.filter = ToShellString(filter)
+		}
+		if sort := getDocValue(command, "sort"); sort != nil {
+			rec.filter += ", sort: " + ToShellString(sort)
+		}
+	case "count", "distinct":
+		rec.filter = ToShellString(getDocValue(command, "query"))
+	case "delete", "update":
+		if rec.op == "delete" && getDocValue(command, "writeConcern") != nil {
+			return rec, false
+		}
+		statements := getDocValue(command, rec.op+"s")
+		if arr, ok := statements.(primitive.A); ok && len(arr) > 0 {
+			rec.filter = ToShellString(getDocValue(arr[0], "q"))
+		}
+	case "aggregate":
+		if rec.filter = getPipelineFilter(getDocValue(command, "pipeline")); rec.filter == "" {
+			if rec.scan != COLLSCAN {
+				return rec, false
+			}
+			rec.filter = "{}" // it's a collection scan without $match or $sort
+		}
+	case "getMore":
+		origin := getDocValue(attr, "originatingCommand")
+		if origin == nil {
+			return rec, false
+		}
+		if filter := getDocValue(origin, "filter"); filter != nil {
+			rec.filter = ToShellString(filter)
+		} else if rec.filter = getPipelineFilter(getDocValue(origin, "pipeline")); rec.filter == "" {
+			return rec, false
+		}
+	}
+	return rec, true
+}
+
+// getPipelineFilter returns $match or $sort of the first stage
+func getPipelineFilter(pipeline interface{}) string {
+	if arr, ok := pipeline.(primitive.A); ok && len(arr) > 0 {
+		for _, stage := range []string{"$match", "$sort"} {
+			if v := getDocValue(arr[0], stage); v != nil {
+				return ToShellString(v)
+			}
+		}
+	}
+	return ""
+}
+
+// getDocValue returns value of a key from a bson.D or a bson.M
+func getDocValue(doc interface{}, key string) interface{} {
+	switch d := doc.(type) {
+	case bson.D:
+		for _, e := range d {
+			if e.Key == key {
+				return e.Value
+			}
+		}
+	case bson.M:
+		return d[key]
+	}
+	return nil
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// ToShellString converts a value to the mongo shell syntax used by legacy logs, e.g. { a: "x", b: { $in: [ 1, 2 ] } }
+func ToShellString(v interface{}) string {
+	switch d := v.(type) {
+	case nil:
+		return "null"
+	case bson.D:
+		if len(d) == 0 {
+			return "{}"
+		}
+		strs := []string{}
+		for _, e := range d {
+			strs = append(strs, e.Key+": "+ToShellString(e.Value))
+		}
+		return "{ " + strings.Join(strs, ", ") + " }"
+	case bson.M:
+		keys := []string{}
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		doc := bson.D{}
+		for _, k := range keys {
+			doc = append(doc, bson.E{Key: k, Value: d[k]})
+		}
+		return ToShellString(doc)
+	case primitive.A:
+		return ToShellString([]interface{}(d))
+	case []interface{}:
+		if len(d) == 0 {
+			return "[]"
+		}
+		strs := []string{}
+		for _, e := range d {
+			strs = append(strs, ToShellString(e))
+		}
+		return "[ " + strings.Join(strs, ", ") + " ]"
+	case string:
+		return strconv.Quote(d)
+	case float64:
+		return strconv.FormatFloat(d, 'f', -1, 64)
+	case primitive.DateTime:
+		return fmt.Sprintf("new Date(%d)", int64(d))
+	case primitive.ObjectID:
+		return "ObjectId('" + d.Hex() + "')"
+	case primitive.Regex:
+		return "/" + d.Pattern + "/" + d.Options
+	case primitive.Timestamp:
+		return fmt.Sprintf("Timestamp(%d, %d)", d.T, d.I)
+	case primitive.Binary:
+		return fmt.Sprintf("BinData(%d, %s)", d.Subtype, base64.StdEncoding.EncodeToString(d.Data))
+	case primitive.Decimal128:
+		return "NumberDecimal(\"" + d.String() + "\")"
+	}
+	return fmt.Sprintf("%v", v)
+}
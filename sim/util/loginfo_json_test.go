@@ -0,0 +1,70 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var jsonLogLines = []string{
+	`{"t":{"$date":"2020-08-10T12:00:00.000+00:00"},"s":"I","c":"CONTROL","id":21951,"ctx":"initandlisten","msg":"Options set by command line","attr":{"options":{"net":{"port":27017},"storage":{"dbPath":"/data/db"}}}}`,
+	`{"t":{"$date":"2020-08-10T12:00:01.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"keyhole.cars","command":{"find":"cars","filter":{"color":"Red","year":{"$gt":2010}},"sort":{"brand":1},"$db":"keyhole"},"planSummary":"IXSCAN { color: 1 }","keysExamined":120,"docsExamined":120,"nreturned":100,"durationMillis":150}}`,
+	`{"t":{"$date":"2020-08-10T12:00:02.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn2","msg":"Slow query","attr":{"type":"command","ns":"keyhole.cars","command":{"find":"cars","filter":{"color":"Blue","year":{"$gt":2015}},"sort":{"brand":1},"$db":"keyhole"},"planSummary":"IXSCAN { color: 1 }","keysExamined":20,"docsExamined":20,"nreturned":10,"durationMillis":250}}`,
+	`{"t":{"$date":"2020-08-10T12:00:03.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn3","msg":"Slow query","attr":{"type":"command","ns":"keyhole.cars","command":{"aggregate":"cars","pipeline":[{"$match":{"brand":{"$in":["BMW","Audi"]}}},{"$group":{"_id":"$color"}}],"$db":"keyhole"},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":5000,"nreturned":5,"durationMillis":12000}}`,
+	`{"t":{"$date":"2020-08-10T12:00:04.000+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn4","msg":"Slow query","attr":{"type":"update","ns":"keyhole.cars","command":{"q":{"_id":{"$oid":"5f3146a8b1e2c3d4e5f60718"}},"u":{"$set":{"sold":true}}},"planSummary":"IDHACK","keysExamined":1,"docsExamined":1,"nModified":1,"durationMillis":120}}`,
+}
+
+func TestParseJSONLogLine(t *testing.T) {
+	rec, ok := parseJSONLogLine(jsonLogLines[1])
+	if ok == false || rec.op != "find" || rec.ns != "keyhole.cars" || rec.milli != 150 || rec.index != "{ color: 1 }" ||
+		rec.keysExamined != 120 || rec.nreturned != 100 || rec.filter != `{ color: "Red", year: { $gt: 2010 } }, sort: { brand: 1 }` {
+		t.Fatal(rec)
+	}
+	rec, ok = parseJSONLogLine(jsonLogLines[3])
+	if ok == false || rec.op != "aggregate" || rec.scan != COLLSCAN || rec.filter != `{ brand: { $in: [ "BMW", "Audi" ] } }` {
+		t.Fatal(rec)
+	}
+	rec, ok = parseJSONLogLine(jsonLogLines[4])
+	if ok == false || rec.op != "update" || rec.index != "IDHACK" || rec.filter != "{ _id: ObjectId('5f3146a8b1e2c3d4e5f60718') }" {
+		t.Fatal(rec)
+	}
+	if _, ok = parseJSONLogLine(jsonLogLines[0]); ok == true {
+		t.Fatal("not a slow query")
+	}
+}
+
+func TestParseJSONLogFile(t *testing.T) {
+	filename := "loginfo_json_test.log"
+	str := ""
+	for _, line := range jsonLogLines {
+		str += line + "\n"
+	}
+	ioutil.WriteFile(filename, []byte(str), 0644)
+	defer os.Remove(filename)
+	li := NewLogInfo(filename)
+	li.SetSilent(true)
+	if err := li.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if len(li.OpsPatterns) != 3 || len(li.SlowOps) != 1 {
+		t.Fatal(li.OpsPatterns, li.SlowOps)
+	}
+	for _, doc := range li.OpsPatterns {
+		if doc.Command == "find" && (doc.Count != 2 || doc.MaxMilli != 250 || doc.TotalMilli != 400) {
+			t.Fatal(doc)
+		}
+	}
+}
+
+func TestParseLegacyLogLine(t *testing.T) {
+	str := "2018-05-18T12:10:47.047+0000 I COMMAND  [conn3683663] command taterstore.recentlyWatched command: find { find: \"recentlyWatched\", filter: { tveUserId: 97018 }, projection: { $sortKey: { $meta: \"sortKey\" } }, sort: { updated: -1 }, shardVersion: [ Timestamp 0|0, ObjectId('000000000000000000000000') ] } planSummary: IXSCAN { tveUserId: 1, updated: -1 } cursorid:262265246743 keysExamined:101 docsExamined:101 numYields:2 nreturned:101 reslen:20674 locks:{ Global: { acquireCount: { r: 6 } }, Database: { acquireCount: { r: 3 } }, Collection: { acquireCount: { r: 3 } } } protocol:op_command 140ms"
+	rec, ok := parseLegacyLogLine(str)
+	if ok == false || rec.op != "find" || rec.milli != 140 || rec.filter != "{ tveUserId: 97018 }, sort: { updated: -1 }" {
+		t.Fatal(rec)
+	}
+	if normalizeFilter(rec.filter) != "{tveUserId:1}, sort: {updated:1}" {
+		t.Fatal(normalizeFilter(rec.filter))
+	}
+}
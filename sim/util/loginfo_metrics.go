@@ -0,0 +1,170 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// docs examined per returned of patterns stand out
+const highExaminedRatio = 100
+
+// opMetrics are execution metrics of a slow op
+type opMetrics struct {
+	bytesRead       int
+	docsExamined    int
+	exhaustive      bool // broadcast to all shards (mongos)
+	hasSortStage    bool
+	inTransaction   bool // of a multi-document transaction
+	keysExamined    int
+	lockWaitMicros  int
+	nreturned       int
+	nShards         int // shards targeted (mongos)
+	numYields       int
+	readMicros      int
+	remoteWaitMilli int // milliseconds waiting for shards (mongos)
+	writeConflicts  int
+}
+
+var legacyMetricMatched = regexp.MustCompile(`\b(keysExamined|docsExamined|nreturned|numYields|hasSortStage|nShards|remoteOpWaitMillis|exhaustive|writeConflicts):(\d+)`)
+var legacyLockWaitMatched = regexp.MustCompile(`timeAcquiringMicros: \{([^}]*)\}`)
+var legacyBytesReadMatched = regexp.MustCompile(`bytesRead: (\d+)`)
+var legacyTimeReadingMatched = regexp.MustCompile(`timeReadingMicros: (\d+)`)
+var digitsMatched = regexp.MustCompile(`\d+`)
+
+// getLegacyMetrics returns execution metrics of a legacy log line
+func getLegacyMetrics(str string) opMetrics {
+	var m opMetrics
+	for _, res := range legacyMetricMatched.FindAllStringSubmatch(str, -1) {
+		n, _ := strconv.Atoi(res[2])
+		switch res[1] {
+		case "keysExamined":
+			m.keysExamined = n
+		case "docsExamined":
+			m.docsExamined = n
+		case "nreturned":
+			m.nreturned = n
+		case "numYields":
+			m.numYields = n
+		case "hasSortStage":
+			m.hasSortStage = n > 0
+		case "nShards":
+			m.nShards = n
+		case "remoteOpWaitMillis":
+			m.remoteWaitMilli = n
+		case "exhaustive":
+			m.exhaustive = n > 0
+		case "writeConflicts":
+			m.writeConflicts = n
+		}
+	}
+	m.inTransaction = strings.Index(str, "txnNumber: ") > 0 && strings.Index(str, "autocommit: false") > 0
+	for _, res := range legacyLockWaitMatched.FindAllStringSubmatch(str, -1) {
+		for _, s := range digitsMatched.FindAllString(res[1], -1) {
+			n, _ := strconv.Atoi(s)
+			m.lockWaitMicros += n
+		}
+	}
+	if res := legacyBytesReadMatched.FindStringSubmatch(str); len(res) > 0 {
+		m.bytesRead, _ = strconv.Atoi(res[1])
+	}
+	if res := legacyTimeReadingMatched.FindStringSubmatch(str); len(res) > 0 {
+		m.readMicros, _ = strconv.Atoi(res[1])
+	}
+	return m
+}
+
+// getJSONMetrics returns execution metrics of attributes of a structured log (4.4+)
+func getJSONMetrics(attr interface{}) opMetrics {
+	m := opMetrics{keysExamined: toInt(getDocValue(attr, "keysExamined")), docsExamined: toInt(getDocValue(attr, "docsExamined")),
+		nreturned: toInt(getDocValue(attr, "nreturned")), numYields: toInt(getDocValue(attr, "numYields")),
+		nShards: toInt(getDocValue(attr, "nShards")), remoteWaitMilli: toInt(getDocValue(attr, "remoteOpWaitMillis"))}
+	m.hasSortStage, _ = getDocValue(attr, "hasSortStage").(bool)
+	m.exhaustive, _ = getDocValue(attr, "exhaustive").(bool)
+	m.writeConflicts = toInt(getDocValue(attr, "writeConflicts"))
+	m.inTransaction = getDocValue(getDocValue(attr, "command"), "txnNumber") != nil
+	if locks, ok := getDocValue(attr, "locks").(bson.D); ok {
+		for _, lock := range locks {
+			if wait, ok := getDocValue(lock.Value, "timeAcquiringMicros").(bson.D); ok {
+				for _, e := range wait {
+					m.lockWaitMicros += toInt(e.Value)
+				}
+			}
+		}
+	}
+	data := getDocValue(getDocValue(attr, "storage"), "data")
+	m.bytesRead = toInt(getDocValue(data, "bytesRead"))
+	m.readMicros = toInt(getDocValue(data, "timeReadingMicros"))
+	return m
+}
+
+// addMetrics adds execution metrics of an op to its pattern
+func (doc *OpPerformanceDoc) addMetrics(m opMetrics) {
+	doc.BytesRead += m.bytesRead
+	doc.DocsExamined += m.docsExamined
+	doc.KeysExamined += m.keysExamined
+	doc.LockWaitMicros += m.lockWaitMicros
+	doc.NReturned += m.nreturned
+	doc.NumYields += m.numYields
+	doc.ReadMicros += m.readMicros
+	doc.SortStages += btoi(m.hasSortStage)
+	doc.TxnOps += btoi(m.inTransaction)
+	doc.WriteConflicts += m.writeConflicts
+	doc.addShards(m)
+}
+
+// mergeMetrics adds execution metrics of another pattern
+func (doc *OpPerformanceDoc) mergeMetrics(other OpPerformanceDoc) {
+	doc.BytesRead += other.BytesRead
+	doc.DocsExamined += other.DocsExamined
+	doc.KeysExamined += other.KeysExamined
+	doc.LockWaitMicros += other.LockWaitMicros
+	doc.NReturned += other.NReturned
+	doc.NumYields += other.NumYields
+	doc.ReadMicros += other.ReadMicros
+	doc.SortStages += other.SortStages
+	doc.TxnOps += other.TxnOps
+	doc.WriteConflicts += other.WriteConflicts
+	doc.mergeShards(other)
+}
+
+// DocsExaminedPerReturned returns ratio of documents examined to documents returned
+func (doc OpPerformanceDoc) DocsExaminedPerReturned() float64 {
+	return getExaminedRatio(doc.DocsExamined, doc.NReturned)
+}
+
+// KeysExaminedPerReturned returns ratio of index keys examined to documents returned
+func (doc OpPerformanceDoc) KeysExaminedPerReturned() float64 {
+	return getExaminedRatio(doc.KeysExamined, doc.NReturned)
+}
+
+func getExaminedRatio(examined int, nreturned int) float64 {
+	if nreturned == 0 {
+		return float64(examined)
+	}
+	return float64(examined) / float64(nreturned)
+}
+
+// getMetricsSummary returns execution metrics of a pattern, averages per op
+func getMetricsSummary(doc OpPerformanceDoc) string {
+	str := fmt.Sprintf("keys/ret %.1f, docs/ret %.1f, returned %d, sorts %d, yields %d, lock wait %sms, read %d bytes in %sms",
+		doc.KeysExaminedPerReturned(), doc.DocsExaminedPerReturned(), doc.NReturned/doc.Count, doc.SortStages,
+		doc.NumYields/doc.Count, trimTime(float64(doc.LockWaitMicros)/1000/float64(doc.Count)), doc.BytesRead/doc.Count,
+		trimTime(float64(doc.ReadMicros)/1000/float64(doc.Count)))
+	if doc.TxnOps > 0 {
+		str += fmt.Sprintf(", in transactions %d", doc.TxnOps)
+	}
+	if doc.WriteConflicts > 0 {
+		str += fmt.Sprintf(", write conflicts %d", doc.WriteConflicts)
+	}
+	return str
+}
+
+func trimTime(milli float64) string {
+	return strconv.FormatFloat(milli, 'f', 1, 64)
+}
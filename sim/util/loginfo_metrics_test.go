@@ -0,0 +1,53 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetLegacyMetrics(t *testing.T) {
+	str := `2019-03-01T10:00:01.000+0000 I COMMAND  [conn1] command keyhole.cars command: find { find: "cars", filter: { color: "Red" } } planSummary: IXSCAN { year: 1 } keysExamined:50000 docsExamined:50000 hasSortStage:1 cursorExhausted:1 numYields:390 nreturned:1 reslen:300 locks:{ Global: { acquireCount: { r: 392 }, acquireWaitCount: { r: 2 }, timeAcquiringMicros: { r: 1500 } }, Database: { timeAcquiringMicros: { r: 500 } } } storage:{ data: { bytesRead: 4096, timeReadingMicros: 300 } } protocol:op_msg 1500ms`
+	rec, ok := parseLegacyLogLine(str)
+	if ok == false {
+		t.Fatal(str)
+	}
+	m := rec.opMetrics
+	if m.keysExamined != 50000 || m.docsExamined != 50000 || m.nreturned != 1 || m.numYields != 390 || m.hasSortStage == false ||
+		m.lockWaitMicros != 2000 || m.bytesRead != 4096 || m.readMicros != 300 {
+		t.Fatal(m)
+	}
+	li := NewLogInfo("mongod.log")
+	agg := newLogAggregator(li)
+	li.parseLogLine(agg, str, false)
+	li.parseLogLine(agg, str, false)
+	li.setResults(agg)
+	doc := li.OpsPatterns[0]
+	if doc.DocsExamined != 100000 || doc.NReturned != 2 || doc.SortStages != 2 || doc.DocsExaminedPerReturned() != 50000 {
+		t.Fatal(doc)
+	}
+	if str = printLogsSummary(li.OpsPatterns); strings.Index(str, "\x1b[31;1mkeys/ret 50000.0, docs/ret 50000.0, returned 1, sorts 2, yields 390, lock wait 2.0ms, read 4096 bytes in 0.3ms") < 0 {
+		t.Fatal(str)
+	}
+}
+
+func TestGetJSONMetrics(t *testing.T) {
+	str := `{"t":{"$date":"2020-08-10T12:00:01.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"keyhole.cars","command":{"find":"cars","filter":{"color":"Red"},"$db":"keyhole"},"planSummary":"IXSCAN { color: 1 }","keysExamined":120,"docsExamined":120,"hasSortStage":true,"numYields":3,"nreturned":100,"locks":{"Global":{"acquireCount":{"r":4},"timeAcquiringMicros":{"r":250}}},"storage":{"data":{"bytesRead":2048,"timeReadingMicros":150}},"durationMillis":150}}`
+	rec, ok := parseJSONLogLine(str)
+	m := rec.opMetrics
+	if ok == false || m.keysExamined != 120 || m.docsExamined != 120 || m.nreturned != 100 || m.numYields != 3 ||
+		m.hasSortStage == false || m.lockWaitMicros != 250 || m.bytesRead != 2048 || m.readMicros != 150 {
+		t.Fatal(m)
+	}
+}
+
+func TestMergeMetrics(t *testing.T) {
+	x := getTestLogInfo("host1:27017")
+	merged := MergeLogInfo([]*LogInfo{x, getTestLogInfo("host2:27017")})
+	for i, doc := range merged.OpsPatterns {
+		if doc.KeysExamined != 2*x.OpsPatterns[i].KeysExamined || doc.NReturned != 2*x.OpsPatterns[i].NReturned {
+			t.Fatal(doc)
+		}
+	}
+}
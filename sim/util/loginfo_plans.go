@@ -0,0 +1,172 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ratio of average milliseconds of the slowest plan to the fastest plan of a flagged query shape
+const planFlipRatio = 5
+
+// number of plan changes listed per query shape
+const planChangesListed = 10
+
+// PlanFlipDoc stores plans of a query shape and when plans changed
+type PlanFlipDoc struct {
+	Command   string                 `json:"command"`
+	Filter    string                 `json:"filter"` // query shape
+	Flagged   bool                   `json:"flagged"`
+	Hash      string                 `json:"hash"`
+	Host      string                 `json:"host,omitempty"` // of merged results
+	Namespace string                 `json:"ns"`
+	Plans     map[string]OpBucketDoc `json:"plans"` // by plan summaries
+	Runs      []PlanRunDoc           `json:"runs"`  // consecutive ops by the same plan, by time
+}
+
+// PlanRunDoc stores consecutive ops of a query shape executed by the same plan
+type PlanRunDoc struct {
+	Count int       `json:"count"`
+	From  time.Time `json:"from"`
+	Plan  string    `json:"plan"`
+	To    time.Time `json:"to"`
+	batch int       // of a worker, runs of different batches are not adjacent
+}
+
+// getPlan returns plan of an op, e.g. IXSCAN { a: 1 }, COLLSCAN, or IDHACK
+func getPlan(rec logRecord) string {
+	if rec.scan == COLLSCAN {
+		return COLLSCAN
+	} else if strings.HasPrefix(rec.index, "{") {
+		return "IXSCAN " + rec.index
+	}
+	return rec.index
+}
+
+// addPlan adds plan of an op to its query shape
+func (agg *logAggregator) addPlan(rec logRecord, filter string) {
+	plan := getPlan(rec)
+	if plan == "" {
+		return
+	}
+	key := getPatternKey(rec.op, rec.ns, filter, "")
+	doc, ok := agg.plansMap[key]
+	if ok == false {
+		doc = PlanFlipDoc{Command: rec.op, Filter: filter, Hash: GetShapeHash(rec.op, filter), Namespace: rec.ns,
+			Plans: map[string]OpBucketDoc{}}
+	}
+	doc.Plans[plan] = mergeBucket(doc.Plans[plan], OpBucketDoc{Count: 1, MaxMilli: rec.milli, TotalMilli: rec.milli})
+	if rec.timestamp.IsZero() == false {
+		n := len(doc.Runs)
+		if n > 0 && doc.Runs[n-1].Plan == plan && doc.Runs[n-1].batch == agg.batch {
+			doc.Runs[n-1].Count++
+			doc.Runs[n-1].To = rec.timestamp
+		} else {
+			doc.Runs = append(doc.Runs, PlanRunDoc{Count: 1, From: rec.timestamp, Plan: plan, To: rec.timestamp, batch: agg.batch})
+		}
+	}
+	agg.plansMap[key] = doc
+}
+
+// mergePlans merges plans of a query shape of another aggregator
+func (agg *logAggregator) mergePlans(key string, value PlanFlipDoc) {
+	doc, ok := agg.plansMap[key]
+	if ok == false {
+		agg.plansMap[key] = value
+		return
+	}
+	for plan, bucket := range value.Plans {
+		doc.Plans[plan] = mergeBucket(doc.Plans[plan], bucket)
+	}
+	doc.Runs = append(doc.Runs, value.Runs...)
+	agg.plansMap[key] = doc
+}
+
+// getPlanFlips returns query shapes executed by more than one plan, runs are sorted by time and adjacent runs of
+// the same plan are combined
+func (agg *logAggregator) getPlanFlips() []PlanFlipDoc {
+	flips := []PlanFlipDoc{}
+	for _, doc := range agg.plansMap {
+		if len(doc.Plans) < 2 {
+			continue
+		}
+		sort.SliceStable(doc.Runs, func(i, j int) bool { return doc.Runs[i].From.Before(doc.Runs[j].From) })
+		runs := []PlanRunDoc{}
+		for _, run := range doc.Runs {
+			if n := len(runs); n > 0 && runs[n-1].Plan == run.Plan {
+				runs[n-1].Count += run.Count
+				runs[n-1].To = run.To
+				continue
+			}
+			run.batch = 0
+			runs = append(runs, run)
+		}
+		doc.Runs = runs
+		doc.Flagged = isPlanFlipFlagged(doc.Plans)
+		flips = append(flips, doc)
+	}
+	sort.Slice(flips, func(i, j int) bool {
+		if flips[i].Flagged != flips[j].Flagged {
+			return flips[i].Flagged
+		}
+		return len(flips[i].Runs) > len(flips[j].Runs)
+	})
+	return flips
+}
+
+// isPlanFlipFlagged returns true if average milliseconds of plans differ by planFlipRatio times or more
+func isPlanFlipFlagged(plans map[string]OpBucketDoc) bool {
+	fastest, slowest := -1.0, 0.0
+	for _, b := range plans {
+		avg := float64(b.TotalMilli) / float64(b.Count)
+		if fastest < 0 || avg < fastest {
+			fastest = avg
+		}
+		if avg > slowest {
+			slowest = avg
+		}
+	}
+	return slowest >= planFlipRatio*fastest && slowest > fastest
+}
+
+// GetPlanFlips returns plans of query shapes executed by more than one plan and when plans changed
+func (li *LogInfo) GetPlanFlips() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("Query shapes executed by more than one plan (flagged if avg ms differ by %dx):\n", planFlipRatio))
+	for _, doc := range li.PlanFlips {
+		flag := ""
+		if doc.Flagged == true {
+			flag = " [FLAGGED]"
+		}
+		host := ""
+		if doc.Host != "" {
+			host = "[" + doc.Host + "] "
+		}
+		buffer.WriteString(fmt.Sprintf("\n%s%s %s %s%s\n", host, doc.Command, doc.Namespace, doc.Filter, flag))
+		plans := make([]string, 0, len(doc.Plans))
+		for plan := range doc.Plans {
+			plans = append(plans, plan)
+		}
+		sort.Slice(plans, func(i, j int) bool { return doc.Plans[plans[i]].Count > doc.Plans[plans[j]].Count })
+		for _, plan := range plans {
+			b := doc.Plans[plan]
+			buffer.WriteString(fmt.Sprintf("  %-48s count: %6d, avg ms: %6s, max ms: %8d\n", plan, b.Count,
+				strings.TrimSpace(MilliToTimeString(float64(b.TotalMilli)/float64(b.Count))), b.MaxMilli))
+		}
+		for i, run := range doc.Runs {
+			if i == 0 {
+				continue
+			} else if i > planChangesListed {
+				buffer.WriteString(fmt.Sprintf("  ... %d more plan changes\n", len(doc.Runs)-1-planChangesListed))
+				break
+			}
+			buffer.WriteString(fmt.Sprintf("  %s changed %s -> %s\n", run.From.UTC().Format("2006-01-02T15:04:05"),
+				doc.Runs[i-1].Plan, run.Plan))
+		}
+	}
+	return buffer.String()
+}
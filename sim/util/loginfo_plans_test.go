@@ -0,0 +1,62 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func getPlanLogLine(second int, plan string, milli int) string {
+	return fmt.Sprintf(`2019-03-01T10:00:%02d.000+0000 I COMMAND  [conn1] command keyhole.cars command: find { find: "cars", filter: { color: "red", year: %d }, $db: "keyhole" } planSummary: %s keysExamined:0 docsExamined:10 nreturned:1 reslen:300 %dms`,
+		second, 2000+second, plan, milli)
+}
+
+func TestGetPlanFlips(t *testing.T) {
+	li := NewLogInfo("mongod.log")
+	agg := newLogAggregator(li)
+	lines := []string{getPlanLogLine(1, "IXSCAN { color: 1 }", 100), getPlanLogLine(2, "IXSCAN { color: 1 }", 120),
+		getPlanLogLine(3, "COLLSCAN", 5000), getPlanLogLine(5, "IXSCAN { color: 1 }", 110)}
+	for _, line := range lines[:2] {
+		li.parseLogLine(agg, line, false)
+	}
+	other := newLogAggregator(li) // another worker
+	other.batch = 1
+	for _, line := range lines[2:] {
+		li.parseLogLine(other, line, false)
+	}
+	merged := newLogAggregator(li)
+	merged.merge(other)
+	merged.merge(agg)
+	li.setResults(merged)
+	if len(li.OpsPatterns) != 2 || len(li.PlanFlips) != 1 {
+		t.Fatal(li.OpsPatterns, li.PlanFlips)
+	}
+	doc := li.PlanFlips[0]
+	if doc.Flagged == false || len(doc.Plans) != 2 || doc.Plans["IXSCAN { color: 1 }"].Count != 3 || doc.Plans[COLLSCAN].MaxMilli != 5000 {
+		t.Fatal(doc)
+	}
+	if len(doc.Runs) != 3 || doc.Runs[0].Count != 2 || doc.Runs[1].Plan != COLLSCAN || doc.Runs[2].From.Second() != 5 {
+		t.Fatal(doc.Runs)
+	}
+	str := li.GetPlanFlips()
+	t.Log(str)
+	for _, s := range []string{"[FLAGGED]", "2019-03-01T10:00:03 changed IXSCAN { color: 1 } -> COLLSCAN",
+		"2019-03-01T10:00:05 changed COLLSCAN -> IXSCAN { color: 1 }"} {
+		if strings.Index(str, s) < 0 {
+			t.Fatal(s, "not found")
+		}
+	}
+}
+
+func TestIsPlanFlipFlagged(t *testing.T) {
+	plans := map[string]OpBucketDoc{"IDHACK": {Count: 2, TotalMilli: 200}, COLLSCAN: {Count: 1, TotalMilli: 300}}
+	if isPlanFlipFlagged(plans) == true {
+		t.Fatal(plans)
+	}
+	plans[COLLSCAN] = OpBucketDoc{Count: 1, TotalMilli: 500}
+	if isPlanFlipFlagged(plans) == false {
+		t.Fatal(plans)
+	}
+}
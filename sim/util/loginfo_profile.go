@@ -0,0 +1,56 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ParseProfileDocs aggregates system.profile documents into query patterns as slow ops of logs
+func (li *LogInfo) ParseProfileDocs(docs []bson.D) {
+	agg := newLogAggregator(li)
+	for _, doc := range docs {
+		if rec, ok := parseProfileDoc(doc); ok && li.isMatched(rec) {
+			agg.add(rec)
+		}
+	}
+	li.setResults(agg)
+}
+
+// parseProfileDoc parses a system.profile document, attributes are the same as slow ops of structured logs
+// except op, millis, numYield and ts
+func parseProfileDoc(doc bson.D) (logRecord, bool) {
+	var rec logRecord
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return rec, false
+	}
+	rec.line = string(data)
+	if t, ok := getDocValue(doc, "ts").(primitive.DateTime); ok {
+		rec.timestamp = time.Unix(0, int64(t)*int64(time.Millisecond))
+	}
+	attr := bson.D{}
+	for _, e := range doc {
+		switch e.Key {
+		case "op":
+			switch e.Value {
+			case "update", "remove":
+				attr = append(attr, bson.E{Key: "type", Value: e.Value})
+			case "command", "getmore", "query":
+				attr = append(attr, bson.E{Key: "type", Value: "command"})
+			default: // insert and killcursors
+				return rec, false
+			}
+		case "millis":
+			attr = append(attr, bson.E{Key: "durationMillis", Value: e.Value})
+		case "numYield":
+			attr = append(attr, bson.E{Key: "numYields", Value: e.Value})
+		default:
+			attr = append(attr, e)
+		}
+	}
+	return parseSlowOpAttr(rec, attr)
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var profileDocs = []string{
+	`{"op":"query","ns":"keyhole.cars","command":{"find":"cars","filter":{"color":"Red","year":{"$gt":2010}},"sort":{"brand":1},"$db":"keyhole"},"keysExamined":120,"docsExamined":120,"hasSortStage":true,"numYield":3,"nreturned":100,"planSummary":"IXSCAN { color: 1 }","millis":150,"ts":{"$date":"2019-03-01T10:00:01.000Z"}}`,
+	`{"op":"query","ns":"keyhole.cars","command":{"find":"cars","filter":{"color":"Blue","year":{"$gt":2015}},"sort":{"brand":1},"$db":"keyhole"},"keysExamined":20,"docsExamined":20,"numYield":1,"nreturned":10,"planSummary":"IXSCAN { color: 1 }","millis":250,"ts":{"$date":"2019-03-01T10:00:02.000Z"}}`,
+	`{"op":"update","ns":"keyhole.cars","command":{"q":{"vin":"WBA123"},"u":{"$set":{"sold":true}},"multi":false,"upsert":false},"keysExamined":0,"docsExamined":5000,"nMatched":1,"nModified":1,"writeConflicts":2,"planSummary":"COLLSCAN","millis":1200,"ts":{"$date":"2019-03-01T10:00:03.000Z"}}`,
+	`{"op":"insert","ns":"keyhole.cars","command":{"insert":"cars","documents":[{"vin":"WBA124"}]},"millis":120,"ts":{"$date":"2019-03-01T10:00:04.000Z"}}`,
+}
+
+func TestParseProfileDocs(t *testing.T) {
+	docs := []bson.D{}
+	for _, str := range profileDocs {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	li := NewLogInfo("keyhole.profile")
+	li.SetExamples(1)
+	li.ParseProfileDocs(docs)
+	if li.OutputFilename != "keyhole.profile.enc" || len(li.OpsPatterns) != 2 {
+		t.Fatal(li.OutputFilename, li.OpsPatterns)
+	}
+	update, find := li.OpsPatterns[0], li.OpsPatterns[1]
+	if update.Command != "update" || update.Scan != COLLSCAN || update.WriteConflicts != 2 || update.Filter != `{vin: <string>}` {
+		t.Fatal(update)
+	}
+	if find.Count != 2 || find.TotalMilli != 400 || find.NumYields != 4 || find.SortStages != 1 || find.Index != "{ color: 1 }" ||
+		len(find.Buckets) != 1 || strings.HasPrefix(find.Examples[0].Log, `{"op":"query"`) == false {
+		t.Fatal(find)
+	}
+
+	li = NewLogInfo("keyhole.profile")
+	li.SetCollscan(true)
+	li.ParseProfileDocs(docs)
+	if len(li.OpsPatterns) != 1 {
+		t.Fatal(li.OpsPatterns)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// addShards adds shards targeted by an op of a mongos log to its pattern
+func (doc *OpPerformanceDoc) addShards(m opMetrics) {
+	if m.nShards == 0 {
+		return
+	}
+	doc.RoutedOps++
+	doc.ShardsTargeted += m.nShards
+	doc.RemoteWaitMilli += m.remoteWaitMilli
+	doc.BroadcastOps += btoi(m.exhaustive)
+	if m.nShards > doc.MaxShards {
+		doc.MaxShards = m.nShards
+	}
+	if doc.MinShards == 0 || m.nShards < doc.MinShards {
+		doc.MinShards = m.nShards
+	}
+}
+
+// mergeShards adds shards targeted of another pattern
+func (doc *OpPerformanceDoc) mergeShards(other OpPerformanceDoc) {
+	if other.RoutedOps == 0 {
+		return
+	}
+	doc.BroadcastOps += other.BroadcastOps
+	doc.RemoteWaitMilli += other.RemoteWaitMilli
+	doc.RoutedOps += other.RoutedOps
+	doc.ShardsTargeted += other.ShardsTargeted
+	if other.MaxShards > doc.MaxShards {
+		doc.MaxShards = other.MaxShards
+	}
+	if doc.MinShards == 0 || other.MinShards < doc.MinShards {
+		doc.MinShards = other.MinShards
+	}
+}
+
+// AvgShards returns average number of shards targeted by ops of a pattern
+func (doc OpPerformanceDoc) AvgShards() float64 {
+	if doc.RoutedOps == 0 {
+		return 0
+	}
+	return float64(doc.ShardsTargeted) / float64(doc.RoutedOps)
+}
+
+// IsBroadcast returns true if all ops of a pattern were exhaustive or targeted all shards of a cluster
+func (doc OpPerformanceDoc) IsBroadcast(shards int) bool {
+	if doc.RoutedOps == 0 {
+		return false
+	}
+	return doc.BroadcastOps == doc.RoutedOps || (shards > 1 && doc.MinShards >= shards)
+}
+
+// GetClusterShards returns number of shards of a cluster, the most shards targeted by an op of a mongos log
+func GetClusterShards(patterns []OpPerformanceDoc) int {
+	shards := 0
+	for _, doc := range patterns {
+		if doc.MaxShards > shards {
+			shards = doc.MaxShards
+		}
+	}
+	return shards
+}
+
+// getShardsSummary returns shards targeted and router-side latency of a pattern, averages per op
+func getShardsSummary(doc OpPerformanceDoc) string {
+	str := fmt.Sprintf("avg %.1f, max %d", doc.AvgShards(), doc.MaxShards)
+	if doc.RemoteWaitMilli > 0 {
+		remote := float64(doc.RemoteWaitMilli) / float64(doc.RoutedOps)
+		str += fmt.Sprintf(", remote wait %sms, router %sms", trimTime(remote),
+			trimTime(float64(doc.TotalMilli)/float64(doc.Count)-remote))
+	}
+	return str
+}
+
+// GetBroadcastPatterns returns query patterns of mongos logs that were sent to all shards
+func (li *LogInfo) GetBroadcastPatterns() string {
+	var buffer bytes.Buffer
+	shards := GetClusterShards(li.OpsPatterns)
+	for _, doc := range li.OpsPatterns {
+		if doc.IsBroadcast(shards) == false {
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("\n%s %s %s\n  count: %d, avg ms: %s, shards: %s\n", doc.Command, doc.Namespace,
+			doc.Filter, doc.Count, strings.TrimSpace(MilliToTimeString(float64(doc.TotalMilli)/float64(doc.Count))),
+			getShardsSummary(doc)))
+	}
+	if buffer.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Broadcast query patterns of %d shards, filters not aligned with shard keys:\n", shards) + buffer.String()
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+var mongosLogLines = []string{
+	`2019-03-01T10:00:01.000+0000 I COMMAND  [conn1] command keyhole.cars appName: "MongoDB Shell" command: find { find: "cars", filter: { color: "Red" }, shardVersion: [ Timestamp(1, 0), ObjectId('5c7a1c2e9b1e8a3f4c2d1e0f') ], $db: "keyhole" } nShards:3 cursorExhausted:1 numYields:0 nreturned:10 reslen:1234 protocol:op_msg 150ms`,
+	`2019-03-01T10:00:02.000+0000 I COMMAND  [conn2] command keyhole.cars appName: "MongoDB Shell" command: find { find: "cars", filter: { color: "Blue" }, $db: "keyhole" } nShards:3 cursorExhausted:1 numYields:0 nreturned:10 reslen:1234 protocol:op_msg 250ms`,
+	`{"t":{"$date":"2020-08-10T12:00:01.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"keyhole.cars","command":{"find":"cars","filter":{"vin":"WBA123"},"$db":"keyhole"},"nShards":1,"remoteOpWaitMillis":80,"nreturned":1,"durationMillis":100}}`,
+}
+
+func TestParseMongosLogLines(t *testing.T) {
+	li := NewLogInfo("mongos.log")
+	agg := newLogAggregator(li)
+	for i, line := range mongosLogLines {
+		li.parseLogLine(agg, line, i == 2)
+	}
+	li.setResults(agg)
+	if len(li.OpsPatterns) != 2 || GetClusterShards(li.OpsPatterns) != 3 {
+		t.Fatal(li.OpsPatterns)
+	}
+	broadcast, targeted := li.OpsPatterns[0], li.OpsPatterns[1]
+	if broadcast.RoutedOps != 2 || broadcast.AvgShards() != 3 || broadcast.IsBroadcast(3) == false || broadcast.Filter != `{color: <string>}` {
+		t.Fatal(broadcast)
+	}
+	if targeted.MaxShards != 1 || targeted.RemoteWaitMilli != 80 || targeted.IsBroadcast(3) == true {
+		t.Fatal(targeted)
+	}
+	str := li.GetSummary()
+	for _, s := range []string{"avg 3.0, max 3, BROADCAST", "avg 1.0, max 1, remote wait 80.0ms, router 20.0ms",
+		"Broadcast query patterns of 3 shards"} {
+		if strings.Index(str, s) < 0 {
+			t.Fatal(s, "not found", str)
+		}
+	}
+}
+
+func TestMergeShards(t *testing.T) {
+	doc := OpPerformanceDoc{}
+	doc.addShards(opMetrics{nShards: 2, exhaustive: true})
+	other := OpPerformanceDoc{}
+	other.addShards(opMetrics{nShards: 4, exhaustive: true})
+	other.addShards(opMetrics{nShards: 3, exhaustive: true})
+	doc.mergeShards(other)
+	if doc.RoutedOps != 3 || doc.MinShards != 2 || doc.MaxShards != 4 || doc.ShardsTargeted != 9 || doc.IsBroadcast(4) == false {
+		t.Fatal(doc)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TxnStatsDoc stores performance data of multi-document transactions
+type TxnStatsDoc struct {
+	Aborts             OpBucketDoc    `json:"aborts"`  // slow abortTransaction commands
+	Commits            OpBucketDoc    `json:"commits"` // slow commitTransaction commands
+	Count              int            `json:"count"`   // slow transactions
+	MaxMilli           int            `json:"maxMilli"`
+	TerminationCauses  map[string]int `json:"terminationCauses"` // e.g. committed and aborted
+	TimeActiveMicros   int            `json:"timeActiveMicros"`
+	TimeInactiveMicros int            `json:"timeInactiveMicros"`
+	TotalMilli         int            `json:"totalMilli"`
+	WriteConflicts     int            `json:"writeConflicts"`
+}
+
+// txnRecord is a slow transaction, or a commitTransaction or an abortTransaction command, of a log line
+type txnRecord struct {
+	cause              string
+	milli              int
+	op                 string // transaction, commitTransaction or abortTransaction
+	timeActiveMicros   int
+	timeInactiveMicros int
+	timestamp          time.Time
+	writeConflicts     int
+}
+
+var legacyTxnMetricMatched = regexp.MustCompile(`\b(timeActiveMicros|timeInactiveMicros|writeConflicts):(\d+)`)
+var legacyTxnCauseMatched = regexp.MustCompile(`\bterminationCause:(\w+)`)
+var legacyTxnCommandMatched = regexp.MustCompile(`command: (commitTransaction|abortTransaction) \{`)
+var legacyMilliMatched = regexp.MustCompile(` (\d+)ms$`)
+
+// parseTxnLogLine parses a slow transaction, or a commitTransaction or an abortTransaction command
+func parseTxnLogLine(str string, isJSON bool) (txnRecord, bool) {
+	if isJSON == true {
+		return parseJSONTxnLogLine(str)
+	}
+	var rec txnRecord
+	if strings.Index(str, " transaction parameters:") > 0 {
+		rec.op = "transaction"
+		for _, res := range legacyTxnMetricMatched.FindAllStringSubmatch(str, -1) {
+			n, _ := strconv.Atoi(res[2])
+			switch res[1] {
+			case "timeActiveMicros":
+				rec.timeActiveMicros = n
+			case "timeInactiveMicros":
+				rec.timeInactiveMicros = n
+			case "writeConflicts":
+				rec.writeConflicts = n
+			}
+		}
+		if res := legacyTxnCauseMatched.FindStringSubmatch(str); len(res) > 0 {
+			rec.cause = res[1]
+		}
+	} else if strings.Index(str, "Transaction { ") < 0 {
+		return rec, false
+	} else if res := legacyTxnCommandMatched.FindStringSubmatch(str); len(res) > 0 {
+		rec.op = res[1]
+	} else {
+		return rec, false
+	}
+	if res := legacyMilliMatched.FindStringSubmatch(str); len(res) > 0 {
+		rec.milli, _ = strconv.Atoi(res[1])
+	}
+	rec.timestamp = getLogLineTime(str)
+	return rec, true
+}
+
+// parseJSONTxnLogLine parses a structured log line (4.4+) of a slow transaction, or a commitTransaction or an
+// abortTransaction command
+func parseJSONTxnLogLine(str string) (txnRecord, bool) {
+	var rec txnRecord
+	if strings.Index(str, `"msg":"transaction"`) < 0 && strings.Index(str, `Transaction":`) < 0 {
+		return rec, false
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+		return rec, false
+	}
+	attr := getDocValue(doc, "attr")
+	msg, _ := getDocValue(doc, "msg").(string)
+	if msg == "transaction" {
+		rec.op = "transaction"
+		rec.cause, _ = getDocValue(attr, "terminationCause").(string)
+		rec.timeActiveMicros = toInt(getDocValue(attr, "timeActiveMicros"))
+		rec.timeInactiveMicros = toInt(getDocValue(attr, "timeInactiveMicros"))
+		rec.writeConflicts = toInt(getDocValue(attr, "writeConflicts"))
+	} else if cmd, ok := getDocValue(attr, "command").(bson.D); msg == "Slow query" && ok && len(cmd) > 0 &&
+		(cmd[0].Key == "commitTransaction" || cmd[0].Key == "abortTransaction") {
+		rec.op = cmd[0].Key
+	} else {
+		return rec, false
+	}
+	rec.milli = toInt(getDocValue(attr, "durationMillis"))
+	rec.timestamp = getLogLineTime(str)
+	return rec, true
+}
+
+// addTxn aggregates a slow transaction or a transaction command
+func (agg *logAggregator) addTxn(rec txnRecord) {
+	bucket := OpBucketDoc{Count: 1, MaxMilli: rec.milli, TotalMilli: rec.milli}
+	switch rec.op {
+	case "commitTransaction":
+		agg.txn.Commits = mergeBucket(agg.txn.Commits, bucket)
+	case "abortTransaction":
+		agg.txn.Aborts = mergeBucket(agg.txn.Aborts, bucket)
+	default:
+		agg.txn.Count++
+		agg.txn.TotalMilli += rec.milli
+		if rec.milli > agg.txn.MaxMilli {
+			agg.txn.MaxMilli = rec.milli
+		}
+		agg.txn.TimeActiveMicros += rec.timeActiveMicros
+		agg.txn.TimeInactiveMicros += rec.timeInactiveMicros
+		agg.txn.WriteConflicts += rec.writeConflicts
+		if rec.cause != "" {
+			if agg.txn.TerminationCauses == nil {
+				agg.txn.TerminationCauses = map[string]int{}
+			}
+			agg.txn.TerminationCauses[rec.cause]++
+		}
+	}
+}
+
+// mergeTxn merges transactions stats of another aggregator
+func (agg *logAggregator) mergeTxn(other TxnStatsDoc) {
+	agg.txn.Aborts = mergeBucket(agg.txn.Aborts, other.Aborts)
+	agg.txn.Commits = mergeBucket(agg.txn.Commits, other.Commits)
+	agg.txn.Count += other.Count
+	agg.txn.TotalMilli += other.TotalMilli
+	if other.MaxMilli > agg.txn.MaxMilli {
+		agg.txn.MaxMilli = other.MaxMilli
+	}
+	agg.txn.TimeActiveMicros += other.TimeActiveMicros
+	agg.txn.TimeInactiveMicros += other.TimeInactiveMicros
+	agg.txn.WriteConflicts += other.WriteConflicts
+	for cause, n := range other.TerminationCauses {
+		if agg.txn.TerminationCauses == nil {
+			agg.txn.TerminationCauses = map[string]int{}
+		}
+		agg.txn.TerminationCauses[cause] += n
+	}
+}
+
+// GetTransactionsSummary returns slow transactions, transaction commands, and write conflicts and ops in
+// transactions by namespaces and patterns
+func (li *LogInfo) GetTransactionsSummary() string {
+	var buffer bytes.Buffer
+	txn := li.Transactions
+	type nsDoc struct{ txnOps, writeConflicts int }
+	namespaces := map[string]nsDoc{}
+	patterns := []OpPerformanceDoc{}
+	for _, doc := range li.OpsPatterns {
+		if doc.TxnOps+doc.WriteConflicts == 0 {
+			continue
+		}
+		ns := namespaces[doc.Namespace]
+		ns.txnOps += doc.TxnOps
+		ns.writeConflicts += doc.WriteConflicts
+		namespaces[doc.Namespace] = ns
+		patterns = append(patterns, doc)
+	}
+	if txn.Count+txn.Commits.Count+txn.Aborts.Count+len(patterns) == 0 {
+		return ""
+	}
+	buffer.WriteString("Transactions and write conflicts:\n")
+	if txn.Count > 0 {
+		causes := []string{}
+		for cause, n := range txn.TerminationCauses {
+			causes = append(causes, fmt.Sprintf("%s %d", cause, n))
+		}
+		sort.Strings(causes)
+		buffer.WriteString(fmt.Sprintf("  slow transactions: %d, avg ms: %s, max ms: %d, avg active ms: %s, avg inactive ms: %s, write conflicts: %d, %s\n",
+			txn.Count, strings.TrimSpace(MilliToTimeString(float64(txn.TotalMilli)/float64(txn.Count))), txn.MaxMilli,
+			trimTime(float64(txn.TimeActiveMicros)/1000/float64(txn.Count)),
+			trimTime(float64(txn.TimeInactiveMicros)/1000/float64(txn.Count)), txn.WriteConflicts, strings.Join(causes, ", ")))
+	}
+	for _, c := range []struct {
+		name   string
+		bucket OpBucketDoc
+	}{{"commitTransaction", txn.Commits}, {"abortTransaction", txn.Aborts}} {
+		if c.bucket.Count > 0 {
+			buffer.WriteString(fmt.Sprintf("  %s: %d, avg ms: %s, max ms: %d\n", c.name, c.bucket.Count,
+				strings.TrimSpace(MilliToTimeString(float64(c.bucket.TotalMilli)/float64(c.bucket.Count))), c.bucket.MaxMilli))
+		}
+	}
+	keys := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		keys = append(keys, ns)
+	}
+	sort.Slice(keys, func(i, j int) bool { return namespaces[keys[i]].writeConflicts > namespaces[keys[j]].writeConflicts })
+	for _, ns := range keys {
+		buffer.WriteString(fmt.Sprintf("  %-40s ops in transactions: %6d, write conflicts: %6d\n", ns, namespaces[ns].txnOps,
+			namespaces[ns].writeConflicts))
+	}
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].WriteConflicts > patterns[j].WriteConflicts })
+	for _, doc := range patterns {
+		buffer.WriteString(fmt.Sprintf("    %s %s %s: ops in transactions %d, write conflicts %d\n", doc.Command, doc.Namespace,
+			doc.Filter, doc.TxnOps, doc.WriteConflicts))
+	}
+	return buffer.String()
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+var txnLogLines = []string{
+	`2019-03-01T10:00:01.000+0000 I TXN      [conn1] transaction parameters:{ lsid: { id: UUID("3c0ad4e4-4a1b-4a9c-9d2b-8c0c5b0c3a1e") }, txnNumber: 1, autocommit: false, readConcern: { level: "snapshot" } }, readTimestamp:Timestamp(0, 0), keysExamined:1 docsExamined:1 nMatched:1 nModified:1 ninserted:0 ndeleted:0 writeConflicts:2 terminationCause:committed timeActiveMicros:2000 timeInactiveMicros:1000 numYields:0 locks:{ Global: { acquireCount: { r: 3, w: 1 } } } 150ms`,
+	`2019-03-01T10:00:02.000+0000 I COMMAND  [conn1] command admin.$cmd command: commitTransaction { commitTransaction: 1, txnNumber: 1, autocommit: false, $db: "admin" } numYields:0 reslen:163 protocol:op_msg 120ms`,
+	`2019-03-01T10:00:03.000+0000 I COMMAND  [conn2] command keyhole.cars command: update { update: "cars", ordered: true, txnNumber: 2, autocommit: false, $db: "keyhole", updates: [ { q: { _id: 1 }, u: { $inc: { sold: 1 } } } ] } planSummary: IDHACK keysExamined:1 docsExamined:1 nMatched:1 nModified:1 writeConflicts:3 numYields:0 reslen:200 110ms`,
+	`{"t":{"$date":"2020-08-10T12:00:01.000+00:00"},"s":"I","c":"TXN","id":51802,"ctx":"conn3","msg":"transaction","attr":{"parameters":{"lsid":{"id":{"$uuid":"3c0ad4e4-4a1b-4a9c-9d2b-8c0c5b0c3a1f"}},"txnNumber":3,"autocommit":false},"writeConflicts":1,"terminationCause":"aborted","timeActiveMicros":3000,"timeInactiveMicros":500,"durationMillis":250}}`,
+	`{"t":{"$date":"2020-08-10T12:00:02.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn3","msg":"Slow query","attr":{"type":"command","ns":"admin.$cmd","command":{"abortTransaction":1,"txnNumber":3,"autocommit":false,"$db":"admin"},"numYields":0,"durationMillis":105}}`,
+}
+
+func TestParseTxnLogLine(t *testing.T) {
+	rec, ok := parseTxnLogLine(txnLogLines[0], false)
+	if ok == false || rec.op != "transaction" || rec.cause != "committed" || rec.milli != 150 || rec.writeConflicts != 2 ||
+		rec.timeActiveMicros != 2000 || rec.timeInactiveMicros != 1000 || rec.timestamp.IsZero() {
+		t.Fatal(rec)
+	}
+	if rec, ok = parseTxnLogLine(txnLogLines[1], false); ok == false || rec.op != "commitTransaction" || rec.milli != 120 {
+		t.Fatal(rec)
+	}
+	if _, ok = parseTxnLogLine(txnLogLines[2], false); ok == true {
+		t.Fatal(txnLogLines[2])
+	}
+	if rec, ok = parseTxnLogLine(txnLogLines[3], true); ok == false || rec.cause != "aborted" || rec.milli != 250 || rec.writeConflicts != 1 {
+		t.Fatal(rec)
+	}
+	if rec, ok = parseTxnLogLine(txnLogLines[4], true); ok == false || rec.op != "abortTransaction" || rec.milli != 105 {
+		t.Fatal(rec)
+	}
+}
+
+func TestGetTransactionsSummary(t *testing.T) {
+	li := NewLogInfo("mongod.log")
+	x, y := newLogAggregator(li), newLogAggregator(li)
+	for i, line := range txnLogLines {
+		if i < 3 {
+			li.parseLogLine(x, line, false)
+		} else {
+			li.parseLogLine(y, line, true)
+		}
+	}
+	x.merge(y)
+	li.setResults(x)
+	txn := li.Transactions
+	if txn.Count != 2 || txn.WriteConflicts != 3 || txn.MaxMilli != 250 || txn.Commits.Count != 1 || txn.Aborts.Count != 1 ||
+		txn.TerminationCauses["committed"] != 1 || txn.TerminationCauses["aborted"] != 1 {
+		t.Fatal(txn)
+	}
+	if len(li.OpsPatterns) != 1 || li.OpsPatterns[0].TxnOps != 1 || li.OpsPatterns[0].WriteConflicts != 3 {
+		t.Fatal(li.OpsPatterns)
+	}
+	str := li.GetTransactionsSummary()
+	t.Log(str)
+	for _, s := range []string{"slow transactions: 2, avg ms: 200", "aborted 1, committed 1", "commitTransaction: 1",
+		"abortTransaction: 1", "ops in transactions:      1, write conflicts:      3"} {
+		if strings.Index(str, s) < 0 {
+			t.Fatal(s, "not found")
+		}
+	}
+}
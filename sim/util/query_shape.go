@@ -0,0 +1,377 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// query shape node kinds
+const (
+	shapeArray = iota
+	shapeDoc
+	shapeValue
+)
+
+// ShapeNode is a node of a parsed mongo shell document
+type ShapeNode struct {
+	Keys  []string     // keys of a document
+	Nodes []*ShapeNode // values of a document or elements of an array
+	Kind  int
+	Text  string // literal of a value
+	Type  string // type of a value, e.g. string, number, date
+}
+
+type shapeToken struct {
+	kind byte // one of {}[]:, or s(tring), w(ord), v(alue)
+	text string
+	typ  string
+}
+
+// keys whose documents are order sensitive and shown as they are
+var orderedShapeKeys = map[string]bool{"sort": true, "$sort": true, "hint": true, "$orderby": true}
+
+// operators whose arrays are sets of values
+var setShapeKeys = map[string]bool{"$in": true, "$nin": true, "$all": true}
+
+// operators whose arrays are commutative
+var commutativeShapeKeys = map[string]bool{"$and": true, "$or": true, "$nor": true}
+
+// GetQueryShape returns canonical shape of a filter logged in mongo shell syntax, e.g.
+// { b: "x", a: { $in: [ 1, 2, 3 ] } }, sort: { c: -1 } becomes {a: {$in: [<number>]}, b: <string>}, sort: {c: -1}
+func GetQueryShape(str string) (string, error) {
+	node, extras, err := ParseShellDoc(str)
+	if err != nil {
+		return "", err
+	}
+	shapes := []string{node.Shape("")}
+	for i, key := range extras.Keys {
+		shapes = append(shapes, key+": "+extras.Nodes[i].Shape(key))
+	}
+	return strings.Join(shapes, ", "), nil
+}
+
+// GetShapeHash returns a stable hash of a command and a query shape
+func GetShapeHash(command string, shape string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(command+" "+shape)))[:16]
+}
+
+// ParseShellDoc parses a value in mongo shell syntax followed by optional key: value pairs,
+// e.g. { a: 1 }, sort: { b: 1 }.  Pairs are returned as a document.
+func ParseShellDoc(str string) (*ShapeNode, *ShapeNode, error) {
+	tokens, err := tokenizeShell(str)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("empty document")
+	}
+	p := &shellParser{tokens: tokens}
+	var node *ShapeNode
+	if node, err = p.parseValue(); err != nil {
+		return nil, nil, err
+	}
+	extras := &ShapeNode{Kind: shapeDoc}
+	for p.pos < len(p.tokens) {
+		if err = p.expect(','); err != nil {
+			return nil, nil, err
+		}
+		if err = p.parsePair(extras); err != nil {
+			return nil, nil, err
+		}
+	}
+	return node, extras, nil
+}
+
+type shellParser struct {
+	tokens []shapeToken
+	pos    int
+}
+
+func (p *shellParser) next() (shapeToken, error) {
+	if p.pos >= len(p.tokens) {
+		return shapeToken{}, errors.New("unexpected end of document")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *shellParser) expect(kind byte) error {
+	t, err := p.next()
+	if err == nil && t.kind != kind {
+		err = fmt.Errorf("expected '%c', got '%v'", kind, t.text)
+	}
+	return err
+}
+
+func (p *shellParser) peek() byte {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].kind
+	}
+	return 0
+}
+
+func (p *shellParser) parsePair(doc *ShapeNode) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.kind != 'w' && t.kind != 's' {
+		return fmt.Errorf("invalid key '%v'", t.text)
+	}
+	if err = p.expect(':'); err != nil {
+		return err
+	}
+	var node *ShapeNode
+	if node, err = p.parseValue(); err != nil {
+		return err
+	}
+	doc.Keys = append(doc.Keys, t.text)
+	doc.Nodes = append(doc.Nodes, node)
+	return nil
+}
+
+func (p *shellParser) parseValue() (*ShapeNode, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case '{':
+		doc := &ShapeNode{Kind: shapeDoc}
+		for p.peek() != '}' {
+			if len(doc.Keys) > 0 {
+				if err = p.expect(','); err != nil {
+					return nil, err
+				}
+			}
+			if err = p.parsePair(doc); err != nil {
+				return nil, err
+			}
+		}
+		p.pos++
+		return doc, nil
+	case '[':
+		arr := &ShapeNode{Kind: shapeArray}
+		for p.peek() != ']' {
+			if len(arr.Nodes) > 0 {
+				if err = p.expect(','); err != nil {
+					return nil, err
+				}
+			}
+			var node *ShapeNode
+			if node, err = p.parseValue(); err != nil {
+				return nil, err
+			}
+			arr.Nodes = append(arr.Nodes, node)
+		}
+		p.pos++
+		return arr, nil
+	case 's':
+		return &ShapeNode{Kind: shapeValue, Text: strconv.Quote(t.text), Type: "string"}, nil
+	case 'v', 'w':
+		return &ShapeNode{Kind: shapeValue, Text: t.text, Type: t.typ}, nil
+	}
+	return nil, fmt.Errorf("unexpected '%v'", t.text)
+}
+
+// IsArray returns true if a node is an array
+func (n *ShapeNode) IsArray() bool {
+	return n.Kind == shapeArray
+}
+
+// IsDoc returns true if a node is a document
+func (n *ShapeNode) IsDoc() bool {
+	return n.Kind == shapeDoc
+}
+
+// Shape returns the canonical shape of a node under a key
+func (n *ShapeNode) Shape(key string) string {
+	if orderedShapeKeys[key] == true {
+		return n.Literal()
+	}
+	switch n.Kind {
+	case shapeDoc:
+		if len(n.Keys) == 0 {
+			return "{}"
+		}
+		strs := make([]string, len(n.Keys))
+		for i, k := range n.Keys {
+			strs[i] = quoteShapeKey(k) + ": " + n.Nodes[i].Shape(k)
+		}
+		sort.Strings(strs)
+		return "{" + strings.Join(strs, ", ") + "}"
+	case shapeArray:
+		if len(n.Nodes) == 0 {
+			return "[]"
+		}
+		isScalar := true
+		strs := make([]string, len(n.Nodes))
+		for i, node := range n.Nodes {
+			strs[i] = node.Shape("")
+			if node.Kind != shapeValue {
+				isScalar = false
+			}
+		}
+		if setShapeKeys[key] == true || isScalar == true { // collapsed into distinct shapes
+			sort.Strings(strs)
+			distinct := []string{}
+			for i, s := range strs {
+				if i == 0 || s != strs[i-1] {
+					distinct = append(distinct, s)
+				}
+			}
+			strs = distinct
+		} else if commutativeShapeKeys[key] == true {
+			sort.Strings(strs)
+		}
+		return "[" + strings.Join(strs, ", ") + "]"
+	}
+	return "<" + n.Type + ">"
+}
+
+// Literal returns a node as it is
+func (n *ShapeNode) Literal() string {
+	strs := make([]string, len(n.Nodes))
+	for i, node := range n.Nodes {
+		strs[i] = node.Literal()
+		if n.Kind == shapeDoc {
+			strs[i] = quoteShapeKey(n.Keys[i]) + ": " + strs[i]
+		}
+	}
+	switch n.Kind {
+	case shapeDoc:
+		return "{" + strings.Join(strs, ", ") + "}"
+	case shapeArray:
+		return "[" + strings.Join(strs, ", ") + "]"
+	}
+	return n.Text
+}
+
+func quoteShapeKey(key string) string {
+	for _, r := range key {
+		if unicode.IsLetter(r) == false && unicode.IsDigit(r) == false && strings.ContainsRune("_$.", r) == false {
+			return strconv.Quote(key)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+// types of literals of mongo shell syntax
+var shellWordTypes = map[string]string{
+	"true": "bool", "false": "bool", "null": "null", "undefined": "null", "MinKey": "minKey", "MaxKey": "maxKey",
+	"NaN": "number", "Infinity": "number", "-Infinity": "number",
+}
+
+// types of constructors of mongo shell syntax
+var shellCallTypes = map[string]string{
+	"Date": "date", "ISODate": "date", "ObjectId": "objectId", "Timestamp": "timestamp", "BinData": "binData",
+	"UUID": "uuid", "NumberLong": "number", "NumberInt": "number", "NumberDecimal": "decimal", "HexData": "binData",
+}
+
+func tokenizeShell(str string) ([]shapeToken, error) {
+	var tokens []shapeToken
+	r := []rune(str)
+	for i := 0; i < len(r); {
+		c := r[i]
+		if unicode.IsSpace(c) {
+			i++
+		} else if strings.ContainsRune("{}[]:,", c) {
+			tokens = append(tokens, shapeToken{kind: byte(c), text: string(c)})
+			i++
+		} else if c == '"' || c == '\'' {
+			j := i + 1
+			var sb strings.Builder
+			for ; j < len(r) && r[j] != c; j++ {
+				if r[j] == '\\' && j+1 < len(r) {
+					j++
+				}
+				sb.WriteRune(r[j])
+			}
+			if j >= len(r) {
+				return tokens, errors.New("unterminated string")
+			}
+			tokens = append(tokens, shapeToken{kind: 's', text: sb.String()})
+			i = j + 1
+		} else if c == '/' {
+			j := i + 1
+			for ; j < len(r) && r[j] != '/'; j++ {
+				if r[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(r) {
+				return tokens, errors.New("unterminated regex")
+			}
+			for j++; j < len(r) && unicode.IsLetter(r[j]); j++ {
+			}
+			tokens = append(tokens, shapeToken{kind: 'v', text: string(r[i:j]), typ: "regex"})
+			i = j
+		} else {
+			j := i
+			for j < len(r) && strings.ContainsRune("{}[]:,()\"' \t", r[j]) == false {
+				j++
+			}
+			word := string(r[i:j])
+			if word == "" {
+				return tokens, fmt.Errorf("unexpected character '%c' at %d", c, i)
+			}
+			if word == "new" { // new Date(...)
+				i = j
+				continue
+			}
+			if j < len(r) && r[j] == '(' { // constructors, e.g. ObjectId('...')
+				k, depth := j, 0
+				for ; k < len(r); k++ {
+					if r[k] == '(' {
+						depth++
+					} else if r[k] == ')' {
+						if depth--; depth == 0 {
+							break
+						}
+					}
+				}
+				if k >= len(r) {
+					return tokens, errors.New("unterminated " + word)
+				}
+				tokens = append(tokens, shapeToken{kind: 'v', text: string(r[i : k+1]), typ: getShellCallType(word)})
+				i = k + 1
+				continue
+			}
+			if word == "Timestamp" { // legacy Timestamp 0|0
+				k := j
+				for k < len(r) && (r[k] == ' ' || unicode.IsDigit(r[k]) || r[k] == '|') {
+					k++
+				}
+				tokens = append(tokens, shapeToken{kind: 'v', text: strings.TrimSpace(string(r[i:k])), typ: "timestamp"})
+				i = k
+				continue
+			}
+			if typ, ok := shellWordTypes[word]; ok {
+				tokens = append(tokens, shapeToken{kind: 'v', text: word, typ: typ})
+			} else if _, err := strconv.ParseFloat(word, 64); err == nil {
+				tokens = append(tokens, shapeToken{kind: 'v', text: word, typ: "number"})
+			} else {
+				tokens = append(tokens, shapeToken{kind: 'w', text: word, typ: "string"})
+			}
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func getShellCallType(name string) string {
+	if typ, ok := shellCallTypes[name]; ok {
+		return typ
+	}
+	return strings.ToLower(name)
+}
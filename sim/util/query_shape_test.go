@@ -0,0 +1,40 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestGetQueryShape(t *testing.T) {
+	tests := map[string]string{
+		`{ tveUserId: 97018 }, sort: { updated: -1 }`:                          `{tveUserId: <number>}, sort: {updated: -1}`,
+		`{ b: "x", a: { $in: [ 1, 2, 3 ] } }`:                                  `{a: {$in: [<number>]}, b: <string>}`,
+		`{ a: { $in: [ { x: 1 }, { x: 2 } ] } }`:                               `{a: {$in: [{x: <number>}]}}`,
+		`{ $or: [ { b: 1 }, { a: "x" } ] }`:                                    `{$or: [{a: <string>}, {b: <number>}]}`,
+		`{ items: { $elemMatch: { sku: "a", qty: { $gt: 2 } } } }`:             `{items: {$elemMatch: {qty: {$gt: <number>}, sku: <string>}}}`,
+		`{ "user.email": "a@b.com", 'weird key': true }`:                       `{"weird key": <bool>, user.email: <string>}`,
+		`{ name: /^abc, def/i, _id: ObjectId('5b5c8f3bf62a0d1f4a9a1c2d') }`:    `{_id: <objectId>, name: <regex>}`,
+		`{ ts: { $gte: new Date(1537228800000) }, v: Timestamp 0|0, n: null }`: `{n: <null>, ts: {$gte: <date>}, v: <timestamp>}`,
+		`{ a: { $regex: "^x\"y", $options: "i" } }`:                            `{a: {$options: <string>, $regex: <string>}}`,
+		`{ tags: [ "a", "b" ], docs: [ { x: 1 }, { y: 1 } ] }`:                 `{docs: [{x: <number>}, {y: <number>}], tags: [<string>]}`,
+		`{}`: `{}`,
+	}
+	for str, shape := range tests {
+		s, err := GetQueryShape(str)
+		if err != nil || s != shape {
+			t.Fatal(str, "=>", s, err)
+		}
+	}
+	if _, err := GetQueryShape(`{ a: { $in: [ 1, 2`); err == nil {
+		t.Fatal("expected error of truncated document")
+	}
+}
+
+func TestGetShapeHash(t *testing.T) {
+	x, _ := GetQueryShape(`{ b: 2, a: { $in: [ 1 ] } }`)
+	y, _ := GetQueryShape(`{ a: { $in: [ 3, 4, 5 ] }, b: 9 }`)
+	if GetShapeHash("find", x) != GetShapeHash("find", y) || GetShapeHash("find", x) == GetShapeHash("count", x) {
+		t.Fatal(x, y)
+	}
+}
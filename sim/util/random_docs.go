@@ -146,18 +146,8 @@ func getMagicString(str string, meta bool) string {
 		return "ATL"[:len(str)]
 	}
 	if meta == true {
-		if str == metaEmail || isEmailAddress(str) {
-			return metaEmail
-		} else if str == metaIP || isIP(str) {
-			return metaIP
-		} else if str == metaSSN || isSSN(str) {
-			return metaSSN
-		} else if str == metaTEL || isPhoneNumber(str) {
-			return metaTEL
-		} else if str == metaDate || isDateString(str) {
-			return metaDate
-		} else if str == metaOID || (len(str) == 24 && isHexString(str)) {
-			return metaOID
+		if metaType := getMetaType(str); metaType != "" {
+			return metaType
 		}
 		// hash string
 		r := []rune(str)
@@ -184,9 +174,33 @@ func getMagicString(str string, meta bool) string {
 	return str[p:] + str[:p]
 }
 
+// getMetaType returns $email, $ip, $ssn, $tel, $date, $oId, or an empty string of a string
+func getMetaType(str string) string {
+	if str == metaEmail || isEmailAddress(str) {
+		return metaEmail
+	} else if str == metaIP || isIP(str) {
+		return metaIP
+	} else if str == metaSSN || isSSN(str) {
+		return metaSSN
+	} else if str == metaTEL || isPhoneNumber(str) {
+		return metaTEL
+	} else if str == metaDate || isDateString(str) {
+		return metaDate
+	} else if str == metaOID || (len(str) == 24 && isHexString(str)) {
+		return metaOID
+	}
+	return ""
+}
+
+var emailMatched = regexp.MustCompile(`^[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9-.]+$`)
+var ipMatched = regexp.MustCompile(`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`)
+var ssnMatched = regexp.MustCompile(`^(\d{3}-?\d{2}-?\d{4}|XXX-XX-XXXX)$`)
+var phoneNumberMatched = regexp.MustCompile(`^(\+\d{1,2}\s)?\(?\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}$`)
+var hexStringMatched = regexp.MustCompile(`^[\da-fA-F]+$`)
+var dateStringMatched = regexp.MustCompile(`^\d{4}\-(0?[1-9]|1[012])\-(0?[1-9]|[12][0-9]|3[01])T.*$`)
+
 func isEmailAddress(str string) bool {
-	var matched = regexp.MustCompile(`^[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9-.]+$`)
-	return matched.MatchString(str)
+	return emailMatched.MatchString(str)
 }
 
 // GetEmailAddress exposes getEmailAddress()
@@ -197,8 +211,7 @@ func GetEmailAddress() string {
 }
 
 func isIP(str string) bool {
-	var matched = regexp.MustCompile(`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`)
-	return matched.MatchString(str)
+	return ipMatched.MatchString(str)
 }
 
 func getIP() string {
@@ -207,8 +220,7 @@ func getIP() string {
 }
 
 func isSSN(str string) bool {
-	var matched = regexp.MustCompile(`^(\d{3}-?\d{2}-?\d{4}|XXX-XX-XXXX)$`)
-	return matched.MatchString(str)
+	return ssnMatched.MatchString(str)
 }
 
 func getSSN() string {
@@ -216,8 +228,7 @@ func getSSN() string {
 }
 
 func isPhoneNumber(str string) bool {
-	var matched = regexp.MustCompile(`^(\+\d{1,2}\s)?\(?\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}$`)
-	return matched.MatchString(str)
+	return phoneNumberMatched.MatchString(str)
 }
 
 func getPhoneNumber() string {
@@ -225,8 +236,7 @@ func getPhoneNumber() string {
 }
 
 func isHexString(str string) bool {
-	var matched = regexp.MustCompile(`^[\da-fA-F]+$`)
-	return matched.MatchString(str)
+	return hexStringMatched.MatchString(str)
 }
 
 func getHexString(n int) string {
@@ -238,8 +248,7 @@ func getHexString(n int) string {
 }
 
 func isDateString(str string) bool {
-	var matched = regexp.MustCompile(`^\d{4}\-(0?[1-9]|1[012])\-(0?[1-9]|[12][0-9]|3[01])T.*$`)
-	return matched.MatchString(str)
+	return dateStringMatched.MatchString(str)
 }
 
 var now = time.Now()
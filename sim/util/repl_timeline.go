@@ -0,0 +1,239 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// replication event types
+const (
+	ReplElectionLost     = "election_lost"
+	ReplElectionStarted  = "election_started"
+	ReplElectionWon      = "election_won"
+	ReplHeartbeatFailure = "heartbeat_failure"
+	ReplMemberState      = "member_state"
+	ReplRollback         = "rollback"
+	ReplStateTransition  = "state_transition"
+	ReplStepDown         = "stepdown"
+	ReplSyncSource       = "sync_source"
+	ReplTooStale         = "too_stale"
+)
+
+// ReplEvent is a replication or an election event of a member
+type ReplEvent struct {
+	Event   string    `json:"event"`
+	Host    string    `json:"host"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// ReplTimeline merges replication events of members' logs
+type ReplTimeline struct {
+	Events    []ReplEvent
+	filenames []string
+}
+
+// replEventRules are checked in order, the first matched substring decides the event type
+var replEventRules = []struct {
+	event   string
+	substrs []string
+}{
+	{ReplTooStale, []string{"too stale"}},
+	{ReplRollback, []string{"rollback", "Rollback", "ROLLBACK"}},
+	{ReplElectionWon, []string{"election succeeded", "Election succeeded"}},
+	{ReplElectionLost, []string{"insufficient votes", "Lost election", "not running for primary", "election failed", "Not becoming primary"}},
+	{ReplElectionStarted, []string{"dry run election", "Starting an election", "running for election", "Conducting a dry run"}},
+	{ReplStepDown, []string{"tepping down", "stepdown", "StepDown", "stepDown"}},
+	{ReplStateTransition, []string{"transition to ", "Replica set state transition"}},
+	{ReplHeartbeatFailure, []string{"Error in heartbeat", "Heartbeat failed", "heartbeat failed", "RS_DOWN"}},
+	{ReplMemberState, []string{"is now in state", "Member is in new state"}},
+	{ReplSyncSource, []string{"sync source", "Sync source", "syncing from", "sync target"}},
+}
+
+var replLegacyMatched = regexp.MustCompile(`^(\S+)\s+\w\s+(REPL|REPL_HB|ELECTION|ROLLBACK)\s+\[[^\]]+\] (.*)$`)
+var replHostMatched = regexp.MustCompile(` host=(\S+)`)
+var replPortMatched = regexp.MustCompile(` port=(\d+)`)
+
+// NewReplTimeline -
+func NewReplTimeline(filenames []string) *ReplTimeline {
+	return &ReplTimeline{filenames: filenames}
+}
+
+// Parse reads events of all logs and sorts them by time
+func (rt *ReplTimeline) Parse() error {
+	rt.Events = []ReplEvent{}
+	for _, filename := range rt.filenames {
+		events, err := parseReplEvents(filename)
+		if err != nil {
+			return err
+		}
+		rt.Events = append(rt.Events, events...)
+	}
+	sort.SliceStable(rt.Events, func(i, j int) bool { return rt.Events[i].Time.Before(rt.Events[j].Time) })
+	return nil
+}
+
+// parseReplEvents reads events of a member's log, a directory or a glob of rotated logs, host is from the startup
+// message or the file name
+func parseReplEvents(pattern string) ([]ReplEvent, error) {
+	var err error
+	var filenames []string
+	if filenames, err = GetLogFilenames(pattern); err != nil {
+		return nil, err
+	}
+	host := ""
+	events := []ReplEvent{}
+	for _, filename := range filenames {
+		var list []ReplEvent
+		var startup string
+		if list, startup, err = readReplEvents(filename); err != nil {
+			return nil, err
+		}
+		if host == "" {
+			host = startup
+		}
+		events = append(events, list...)
+	}
+	if host == "" {
+		host = filepath.Base(filenames[len(filenames)-1])
+		if fi, serr := os.Stat(pattern); serr == nil && fi.IsDir() {
+			host = filepath.Base(pattern)
+		}
+	}
+	for i := range events {
+		events[i].Host = host
+	}
+	return events, nil
+}
+
+// readReplEvents reads events and the startup host of a log file
+func readReplEvents(filename string) ([]ReplEvent, string, error) {
+	var err error
+	var file *os.File
+	var reader *bufio.Reader
+	if file, err = os.Open(filename); err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	if reader, _, err = NewCountingReader(file); err != nil {
+		return nil, "", err
+	}
+	host := ""
+	events := []ReplEvent{}
+	for {
+		var buf []byte
+		var isPrefix bool
+		buf, isPrefix, err = reader.ReadLine() // 0x0A separator = newline
+		str := string(buf)
+		for isPrefix == true {
+			var bbuf []byte
+			bbuf, isPrefix, err = reader.ReadLine()
+			str += string(bbuf)
+		}
+		if err != nil {
+			break
+		}
+		if host == "" {
+			host = getStartupHost(str)
+		}
+		if ev, ok := parseReplEvent(str); ok {
+			events = append(events, ev)
+		}
+	}
+	if err != io.EOF {
+		return events, host, err
+	}
+	return events, host, nil
+}
+
+func getStartupHost(str string) string {
+	if strings.Index(str, "MongoDB starting") < 0 {
+		return ""
+	}
+	if strings.HasPrefix(str, "{") {
+		var doc struct {
+			Attr struct {
+				Host string `json:"host"`
+				Port int    `json:"port"`
+			} `json:"attr"`
+		}
+		if json.Unmarshal([]byte(str), &doc) == nil && doc.Attr.Host != "" {
+			return fmt.Sprintf("%v:%v", doc.Attr.Host, doc.Attr.Port)
+		}
+	} else if res := replHostMatched.FindStringSubmatch(str); len(res) > 0 {
+		if port := replPortMatched.FindStringSubmatch(str); len(port) > 0 {
+			return res[1] + ":" + port[1]
+		}
+		return res[1]
+	}
+	return ""
+}
+
+// parseReplEvent parses a legacy or a structured log line of REPL, ELECTION or ROLLBACK components
+func parseReplEvent(str string) (ReplEvent, bool) {
+	var ev ReplEvent
+	if strings.HasPrefix(str, "{") {
+		if strings.Index(str, `"c":"REPL`) < 0 && strings.Index(str, `"c":"ELECTION"`) < 0 && strings.Index(str, `"c":"ROLLBACK"`) < 0 {
+			return ev, false
+		}
+		var doc struct {
+			T struct {
+				Date string `json:"$date"`
+			} `json:"t"`
+			Msg  string          `json:"msg"`
+			Attr json.RawMessage `json:"attr"`
+		}
+		if json.Unmarshal([]byte(str), &doc) != nil {
+			return ev, false
+		}
+		ev.Time, _ = time.Parse(time.RFC3339, doc.T.Date)
+		ev.Message = doc.Msg
+		if len(doc.Attr) > 0 {
+			ev.Message += " " + string(doc.Attr)
+		}
+	} else {
+		res := replLegacyMatched.FindStringSubmatch(str)
+		if len(res) == 0 {
+			return ev, false
+		}
+		ev.Time, _ = time.Parse(legacyTimeLayout, res[1])
+		ev.Message = res[3]
+	}
+	for _, rule := range replEventRules {
+		for _, substr := range rule.substrs {
+			if strings.Index(ev.Message, substr) >= 0 {
+				ev.Event = rule.event
+				return ev, true
+			}
+		}
+	}
+	return ev, false
+}
+
+// GetTimeline returns events in text or json format
+func (rt *ReplTimeline) GetTimeline(format string) (string, error) {
+	if format == "json" {
+		data, err := json.MarshalIndent(rt.Events, "", "  ")
+		return string(data), err
+	}
+	var buffer bytes.Buffer
+	for _, ev := range rt.Events {
+		message := ev.Message
+		if len(message) > 160 {
+			message = message[:157] + "..."
+		}
+		buffer.WriteString(fmt.Sprintf("%-24s %-24s %-18s %s\n", ev.Time.UTC().Format("2006-01-02T15:04:05.000Z"), ev.Host, ev.Event, message))
+	}
+	return buffer.String(), nil
+}
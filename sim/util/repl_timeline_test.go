@@ -0,0 +1,84 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplTimeline(t *testing.T) {
+	member1 := []string{
+		`2019-03-01T10:00:00.000+0000 I CONTROL  [initandlisten] MongoDB starting : pid=1 port=27017 dbpath=/data/db 64-bit host=rs1`,
+		`2019-03-01T10:05:00.000+0000 I REPL     [replexec-1] Error in heartbeat (requestId: 12) to rs2:27017, response status: HostUnreachable`,
+		`2019-03-01T10:05:10.000+0000 I REPL     [replexec-1] Starting an election, since we've seen no PRIMARY in the past 10000ms`,
+		`2019-03-01T10:05:11.000+0000 I REPL     [replexec-2] election succeeded, assuming primary role in term 2`,
+		`2019-03-01T10:05:11.001+0000 I REPL     [replexec-2] transition to PRIMARY from SECONDARY`,
+		`2019-03-01T10:05:12.000+0000 I COMMAND  [conn1] command admin.$cmd command: isMaster { isMaster: 1 } 0ms`,
+	}
+	member2 := []string{
+		`{"t":{"$date":"2019-03-01T10:00:00.000+00:00"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":2,"port":27017,"dbPath":"/data/db","host":"rs2"}}`,
+		`{"t":{"$date":"2019-03-01T10:05:05.000+00:00"},"s":"I","c":"REPL","id":21358,"ctx":"ReplCoord-0","msg":"Replica set state transition","attr":{"newState":"SECONDARY","oldState":"PRIMARY"}}`,
+		`{"t":{"$date":"2019-03-01T10:06:00.000+00:00"},"s":"I","c":"REPL","id":21799,"ctx":"BackgroundSync","msg":"Sync source candidate chosen","attr":{"syncSource":"rs1:27017"}}`,
+	}
+	files := map[string][]string{"repl_timeline_test_1.log": member1, "repl_timeline_test_2.log": member2}
+	for filename, lines := range files {
+		ioutil.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+		defer os.Remove(filename)
+	}
+	rt := NewReplTimeline([]string{"repl_timeline_test_1.log", "repl_timeline_test_2.log"})
+	if err := rt.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{ReplHeartbeatFailure, ReplStateTransition, ReplElectionStarted, ReplElectionWon, ReplStateTransition, ReplSyncSource}
+	hosts := []string{"rs1:27017", "rs2:27017", "rs1:27017", "rs1:27017", "rs1:27017", "rs2:27017"}
+	if len(rt.Events) != len(expected) {
+		t.Fatal(rt.Events)
+	}
+	for i, ev := range rt.Events {
+		if ev.Event != expected[i] || ev.Host != hosts[i] {
+			t.Fatal(i, ev)
+		}
+	}
+	str, err := rt.GetTimeline("json")
+	var events []ReplEvent
+	if err != nil || json.Unmarshal([]byte(str), &events) != nil || len(events) != len(expected) {
+		t.Fatal(err, str)
+	}
+	if str, _ = rt.GetTimeline("text"); strings.Index(str, "2019-03-01T10:05:11.000Z rs1:27017                election_won") < 0 {
+		t.Fatal(str)
+	}
+}
+
+func TestReplTimelineRotatedLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyhole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file, err := os.Create(filepath.Join(dir, "mongod.log.1.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(file)
+	fmt.Fprintln(zw, `2019-03-01T10:00:00.000+0000 I CONTROL  [initandlisten] MongoDB starting : pid=1 port=27017 dbpath=/data/db 64-bit host=rs1`)
+	fmt.Fprintln(zw, `2019-03-01T10:05:10.000+0000 I REPL     [replexec-1] Starting an election, since we've seen no PRIMARY in the past 10000ms`)
+	zw.Close()
+	file.Close()
+	ioutil.WriteFile(filepath.Join(dir, "mongod.log"), []byte(`2019-03-01T11:05:11.000+0000 I REPL     [replexec-2] transition to PRIMARY from SECONDARY`+"\n"), 0644)
+	for _, pattern := range []string{dir, filepath.Join(dir, "mongod.log*")} {
+		rt := NewReplTimeline([]string{pattern})
+		if err = rt.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		if len(rt.Events) != 2 || rt.Events[0].Event != ReplElectionStarted || rt.Events[1].Host != "rs1:27017" {
+			t.Fatal(pattern, rt.Events)
+		}
+	}
+}
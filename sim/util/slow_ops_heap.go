@@ -0,0 +1,44 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// slowOpsHeap is a min-heap of slow ops by milliseconds, the fastest is at the root
+type slowOpsHeap []SlowOps
+
+func (h slowOpsHeap) Len() int            { return len(h) }
+func (h slowOpsHeap) Less(i, j int) bool  { return h[i].Milli < h[j].Milli }
+func (h slowOpsHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *slowOpsHeap) Push(x interface{}) { *h = append(*h, x.(SlowOps)) }
+func (h *slowOpsHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// pushSlowOps keeps the slowest n ops in a bounded heap
+func pushSlowOps(ops []SlowOps, n int, op SlowOps) []SlowOps {
+	if n <= 0 {
+		return ops
+	}
+	h := slowOpsHeap(ops)
+	if len(h) < n {
+		heap.Push(&h, op)
+	} else if op.Milli > h[0].Milli {
+		h[0] = op
+		heap.Fix(&h, 0)
+	}
+	return []SlowOps(h)
+}
+
+// sortSlowOps sorts ops of a heap, the slowest first
+func sortSlowOps(ops []SlowOps) []SlowOps {
+	sorted := append([]SlowOps{}, ops...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Milli > sorted[j].Milli })
+	return sorted
+}
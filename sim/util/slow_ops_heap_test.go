@@ -0,0 +1,48 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestPushSlowOps(t *testing.T) {
+	var ops []SlowOps
+	for _, milli := range []int{300, 100, 500, 200, 400} {
+		ops = pushSlowOps(ops, 3, SlowOps{Milli: milli})
+	}
+	if len(ops) != 3 {
+		t.Fatal("expected 3, but got", len(ops))
+	}
+	sorted := sortSlowOps(ops)
+	for i, milli := range []int{500, 400, 300} {
+		if sorted[i].Milli != milli {
+			t.Fatal("expected", milli, "but got", sorted[i].Milli)
+		}
+	}
+	if ops = pushSlowOps(nil, 0, SlowOps{Milli: 100}); len(ops) != 0 {
+		t.Fatal("expected 0, but got", len(ops))
+	}
+}
+
+func TestLogAggregatorExamples(t *testing.T) {
+	li := NewLogInfo("mongod.log")
+	li.SetSlowMilli(0)
+	li.SetTop(1)
+	li.SetExamples(1)
+	agg := newLogAggregator(li)
+	for _, line := range jsonLogLines {
+		li.parseLogLine(agg, line, true)
+	}
+	if len(agg.slowOps) != 1 {
+		t.Fatal("expected 1, but got", len(agg.slowOps))
+	}
+	for _, doc := range agg.opsMap {
+		if len(doc.Examples) != 1 {
+			t.Fatal("expected 1, but got", len(doc.Examples))
+		}
+		if doc.Examples[0].Milli != doc.MaxMilli {
+			t.Fatal("expected", doc.MaxMilli, "but got", doc.Examples[0].Milli)
+		}
+	}
+}
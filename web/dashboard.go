@@ -0,0 +1,371 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+// DashboardHTML is the built-in multi-panel dashboard.  It is self-contained,
+// no external scripts, and reads data points from the /grafana endpoints.
+var DashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Keyhole - FTDC Dashboard</title>
+<style>
+html *{font-family:Arial,Helvetica,sans-serif}
+body{margin:0;background:#f4f5f5;color:#333}
+#toolbar{position:sticky;top:0;z-index:2;background:#3b4151;color:#fff;padding:8px 12px;font-size:13px}
+#toolbar span.title{font-weight:bold;font-size:15px;margin-right:16px}
+#toolbar button,#toolbar select{margin-left:6px;font-size:12px}
+#toolbar details{display:inline-block;margin-left:12px;position:relative}
+#toolbar details summary{cursor:pointer}
+#toolbar details div{position:absolute;background:#fff;color:#333;border:1px solid #ccc;padding:6px;max-height:360px;overflow:auto;white-space:nowrap}
+#range{margin-left:12px;color:#ff0}
+#panels{display:flex;flex-wrap:wrap;padding:6px}
+.panel{box-sizing:border-box;width:50%;padding:6px}
+.panel .box{background:#fff;border:1px solid #ddd;border-radius:3px;padding:6px}
+.panel h4{margin:0 0 4px 0;font-size:13px}
+.panel svg{width:100%;height:220px;cursor:crosshair;user-select:none}
+.legend{font-size:11px}
+.legend span{display:inline-block;margin-right:10px;cursor:pointer}
+.legend span.off{opacity:.35}
+.legend i{display:inline-block;width:10px;height:10px;margin-right:3px}
+.axis text{font-size:10px;fill:#666}
+.grid{stroke:#eee}
+.brush{fill:rgba(59,65,81,.15);stroke:#3b4151}
+.cursor{stroke:#999;stroke-dasharray:3,3}
+@media (max-width:1000px){.panel{width:100%}}
+</style>
+</head>
+<body>
+<div id="toolbar">
+  <span class="title">Keyhole FTDC</span>
+  <span id="hostinfo"></span>
+  <span id="range"></span>
+  <button id="zoomout">Zoom out</button>
+  <button id="reset">Reset</button>
+  <details><summary>Panels</summary><div id="panelmenu"></div></details>
+  <details id="hosts" style="display:none"><summary>Hosts</summary><div id="hostmenu"></div></details>
+  <select id="downsample"><option value="minmax">min/max</option><option value="lttb">LTTB</option><option value="avg">average</option><option value="last">last</option></select>
+  <select id="metric"><option value="">+ add metric</option></select>
+</div>
+<div id="panels"></div>
+<script>
+(function() {
+  var colors = ["#1f77b4","#ff7f0e","#2ca02c","#d62728","#9467bd","#8c564b","#e377c2","#7f7f7f","#bcbd22","#17becf"];
+  var layout = [
+    {id:"memory", title:"Memory (GB)", targets:["mem_resident","mem_virtual"]},
+    {id:"page_faults", title:"Page Faults", targets:["mem_page_faults"]},
+    {id:"connections", title:"Connections", targets:["conns_current","conns_available","conns_created_per_minute"]},
+    {id:"ops", title:"Ops Counters", targets:["ops_query","ops_insert","ops_update","ops_delete","ops_getmore","ops_command"]},
+    {id:"queues", title:"Queues", targets:["q_active_read","q_active_write","q_queued_read","q_queued_write"]},
+    {id:"latencies", title:"Latencies (milliseconds)", targets:["latency_read","latency_write","latency_command"]},
+    {id:"metrics", title:"Scan Metrics", targets:["scan_keys","scan_objects","scan_sort"]},
+    {id:"wiredtiger_cache", title:"WiredTiger Cache (GB)", targets:["wt_cache_max","wt_cache_used","wt_cache_dirty"]},
+    {id:"wiredtiger_paging", title:"WiredTiger Paging (pages per minute)", targets:["wt_modified_evicted","wt_unmodified_evicted","wt_read_in_cache","wt_written_from_cache"]},
+    {id:"wiredtiger_tickets", title:"WiredTiger Tickets", targets:["ticket_avail_read","ticket_avail_write"]},
+    {id:"cpu", title:"CPU (%)", targets:["cpu_user","cpu_system","cpu_iowait","cpu_nice","cpu_softirq","cpu_steal"]},
+    {id:"disks_utils", title:"Disk Utilization (%)", targets:["disks_utils"]},
+    {id:"disks_iops", title:"Disk IOPS", targets:["disks_iops"]},
+    {id:"repl_lags", title:"Replication Lags (seconds)", targets:["replication_lags"], hosts:true},
+    {id:"slow_ops", title:"Slow Ops from Logs", targets:["slowops_count","slowops_avg_ms","slowops_max_ms"]},
+    {id:"slow_patterns", title:"Slow Query Patterns (avg ms)", targets:["slowops_patterns_avg_ms"]}
+  ];
+  var state = {full:null, from:null, to:null, history:[], hidden:{}, hosts:{}, off:{}};
+  var panels = [];
+
+  function el(tag, attrs, parent) {
+    var ns = (tag == "svg" || tag == "line" || tag == "path" || tag == "rect" || tag == "text" || tag == "g");
+    var e = ns ? document.createElementNS("http://www.w3.org/2000/svg", tag) : document.createElement(tag);
+    for (var k in attrs || {}) {
+      if (k == "text") { e.textContent = attrs[k]; } else { e.setAttribute(k, attrs[k]); }
+    }
+    if (parent) { parent.appendChild(e); }
+    return e;
+  }
+
+  function post(url, body, cb) {
+    var xhr = new XMLHttpRequest();
+    xhr.open("POST", url);
+    xhr.setRequestHeader("Content-Type", "application/json");
+    xhr.onload = function() { if (xhr.status == 200) { cb(JSON.parse(xhr.responseText)); } };
+    xhr.send(JSON.stringify(body));
+  }
+
+  function query(targets, type, cb) {
+    var from = state.from || new Date(0), to = state.to || new Date();
+    var list = targets.map(function(t) { return {target:t, refId:t, type:type}; });
+    var width = Math.round(document.getElementById("panels").clientWidth / 2) || 1000;
+    post("grafana/query", {range:{from:from.toISOString(), to:to.toISOString()}, targets:list,
+      maxDataPoints:width, downsample:document.getElementById("downsample").value}, cb);
+  }
+
+  function fmtTime(ms) {
+    var d = new Date(ms);
+    return d.toISOString().replace("T", " ").substring(0, 19);
+  }
+
+  function fmtValue(v) {
+    var a = Math.abs(v);
+    if (a >= 1e9) { return (v / 1e9).toFixed(1) + "G"; }
+    if (a >= 1e6) { return (v / 1e6).toFixed(1) + "M"; }
+    if (a >= 1e3) { return (v / 1e3).toFixed(1) + "K"; }
+    if (a > 0 && a < 10) { return v.toFixed(2); }
+    return v.toFixed(0);
+  }
+
+  function createPanel(def) {
+    var div = el("div", {"class":"panel", id:def.id}, document.getElementById("panels"));
+    var box = el("div", {"class":"box"}, div);
+    el("h4", {text:def.title}, box);
+    var svg = el("svg", {}, box);
+    var legend = el("div", {"class":"legend"}, box);
+    var p = {def:def, div:div, svg:svg, legend:legend, series:[]};
+    bindBrush(p);
+    panels.push(p);
+    return p;
+  }
+
+  function visibleSeries(p) {
+    return p.series.filter(function(s) {
+      if (state.off[s.target]) { return false; }
+      if (p.def.hosts && state.hosts[s.target] === false) { return false; }
+      return true;
+    });
+  }
+
+  function draw(p) {
+    var svg = p.svg, w = svg.clientWidth || 600, h = svg.clientHeight || 220;
+    var m = {l:50, r:10, t:8, b:20};
+    while (svg.firstChild) { svg.removeChild(svg.firstChild); }
+    p.geom = {w:w, h:h, m:m};
+    var list = visibleSeries(p);
+    var x0 = state.from ? state.from.getTime() : Infinity, x1 = state.to ? state.to.getTime() : -Infinity;
+    var y1 = 0;
+    p.series.forEach(function(s) {
+      s.datapoints.forEach(function(dp) {
+        if (!state.from) { x0 = Math.min(x0, dp[1]); x1 = Math.max(x1, dp[1]); }
+      });
+    });
+    list.forEach(function(s) { s.datapoints.forEach(function(dp) { y1 = Math.max(y1, dp[0]); }); });
+    if (!isFinite(x0) || !isFinite(x1) || x0 == x1) { x0 = x0 || 0; x1 = x0 + 1; }
+    if (y1 == 0) { y1 = 1; }
+    p.geom.x0 = x0; p.geom.x1 = x1;
+    var sx = function(t) { return m.l + (t - x0) * (w - m.l - m.r) / (x1 - x0); };
+    var sy = function(v) { return h - m.b - v * (h - m.t - m.b) / y1; };
+    var axis = el("g", {"class":"axis"}, svg);
+    for (var i = 0; i <= 4; i++) {
+      var v = y1 * i / 4, y = sy(v);
+      el("line", {"class":"grid", x1:m.l, x2:w - m.r, y1:y, y2:y}, axis);
+      el("text", {x:m.l - 4, y:y + 3, "text-anchor":"end", text:fmtValue(v)}, axis);
+    }
+    for (var j = 0; j <= 4; j++) {
+      var t = x0 + (x1 - x0) * j / 4, x = sx(t);
+      el("text", {x:x, y:h - 4, "text-anchor":j == 0 ? "start" : (j == 4 ? "end" : "middle"), text:fmtTime(t).substring(5, 16)}, axis);
+    }
+    p.series.forEach(function(s, n) {
+      s.color = colors[n % colors.length];
+      if (list.indexOf(s) < 0) { return; }
+      var d = "";
+      s.datapoints.forEach(function(dp, k) {
+        d += (k == 0 ? "M" : "L") + sx(dp[1]).toFixed(1) + "," + sy(dp[0]).toFixed(1);
+      });
+      if (d != "") { el("path", {d:d, fill:"none", stroke:s.color, "stroke-width":1.5}, svg); }
+    });
+    p.cursor = el("line", {"class":"cursor", y1:m.t, y2:h - m.b, x1:-10, x2:-10}, svg);
+    p.brush = el("rect", {"class":"brush", x:0, y:m.t, width:0, height:h - m.t - m.b, visibility:"hidden"}, svg);
+    drawLegend(p, null);
+  }
+
+  function drawLegend(p, t) {
+    var legend = p.legend;
+    while (legend.firstChild) { legend.removeChild(legend.firstChild); }
+    p.series.forEach(function(s) {
+      if (p.def.hosts && state.hosts[s.target] === false) { return; }
+      var span = el("span", {"class":state.off[s.target] ? "off" : ""}, legend);
+      el("i", {style:"background:" + s.color}, span);
+      var label = s.target;
+      if (t != null) {
+        var dp = nearest(s.datapoints, t);
+        if (dp) { label += ": " + fmtValue(dp[0]); }
+      }
+      span.appendChild(document.createTextNode(label));
+      span.onclick = function() { state.off[s.target] = !state.off[s.target]; draw(p); };
+    });
+  }
+
+  function nearest(dps, t) {
+    var lo = 0, hi = dps.length - 1;
+    if (hi < 0) { return null; }
+    while (lo < hi) {
+      var mid = (lo + hi) >> 1;
+      if (dps[mid][1] < t) { lo = mid + 1; } else { hi = mid; }
+    }
+    return dps[lo];
+  }
+
+  function toTime(p, px) {
+    var g = p.geom;
+    return g.x0 + (px - g.m.l) * (g.x1 - g.x0) / (g.w - g.m.l - g.m.r);
+  }
+
+  function bindBrush(p) {
+    var start = null;
+    function pos(evt) { return evt.clientX - p.svg.getBoundingClientRect().left; }
+    p.svg.addEventListener("mousedown", function(evt) { start = pos(evt); });
+    p.svg.addEventListener("mousemove", function(evt) {
+      var x = pos(evt), t = toTime(p, x);
+      panels.forEach(function(q) {
+        if (!q.geom) { return; }
+        var qx = q.geom.m.l + (t - q.geom.x0) * (q.geom.w - q.geom.m.l - q.geom.m.r) / (q.geom.x1 - q.geom.x0);
+        q.cursor.setAttribute("x1", qx);
+        q.cursor.setAttribute("x2", qx);
+        drawLegend(q, t);
+      });
+      document.getElementById("range").textContent = fmtTime(t);
+      if (start != null) {
+        p.brush.setAttribute("visibility", "visible");
+        p.brush.setAttribute("x", Math.min(start, x));
+        p.brush.setAttribute("width", Math.abs(x - start));
+      }
+    });
+    p.svg.addEventListener("mouseleave", function() { showRange(); });
+    window.addEventListener("mouseup", function(evt) {
+      if (start == null) { return; }
+      var x = pos(evt), a = Math.min(start, x), b = Math.max(start, x);
+      start = null;
+      p.brush.setAttribute("visibility", "hidden");
+      if (b - a < 5) { return; }
+      zoom(new Date(toTime(p, a)), new Date(toTime(p, b)));
+    });
+    p.svg.addEventListener("dblclick", function() { zoomOut(); });
+  }
+
+  function zoom(from, to) {
+    state.history.push([state.from, state.to]);
+    state.from = from;
+    state.to = to;
+    refresh();
+  }
+
+  function zoomOut() {
+    if (!state.full) { return; }
+    var r = state.history.pop() || [state.full.from, state.full.to];
+    state.from = r[0];
+    state.to = r[1];
+    refresh();
+  }
+
+  function showRange() {
+    if (state.from) {
+      document.getElementById("range").textContent = fmtTime(state.from.getTime()) + " - " + fmtTime(state.to.getTime());
+    }
+  }
+
+  function refresh() {
+    showRange();
+    panels.forEach(function(p) {
+      if (state.hidden[p.def.id]) { p.div.style.display = "none"; return; }
+      p.div.style.display = "";
+      query(p.def.targets, "timeserie", function(data) {
+        p.series = data || [];
+        p.series.sort(function(a, b) { return a.target < b.target ? -1 : 1; });
+        if (p.def.hosts) { buildHostMenu(); }
+        draw(p);
+      });
+    });
+  }
+
+  function buildMenus(available) {
+    var menu = document.getElementById("panelmenu");
+    panels.forEach(function(p) {
+      var label = el("label", {}, menu);
+      var cb = el("input", {type:"checkbox"}, label);
+      cb.checked = !state.hidden[p.def.id];
+      cb.onchange = function() { state.hidden[p.def.id] = !cb.checked; refresh(); };
+      label.appendChild(document.createTextNode(" " + p.def.title));
+      el("br", {}, menu);
+    });
+    var sel = document.getElementById("metric");
+    available.forEach(function(name) {
+      if (name != "host_info") { el("option", {value:name, text:name}, sel); }
+    });
+    sel.onchange = function() {
+      if (sel.value == "") { return; }
+      var custom = panels.filter(function(p) { return p.def.id == "custom"; })[0];
+      if (!custom) {
+        custom = createPanel({id:"custom", title:"Selected Metrics", targets:[]});
+        custom.div.parentNode.insertBefore(custom.div, custom.div.parentNode.firstChild);
+      }
+      if (custom.def.targets.indexOf(sel.value) < 0) { custom.def.targets.push(sel.value); }
+      sel.value = "";
+      refresh();
+    };
+  }
+
+  function buildHostMenu() {
+    var menu = document.getElementById("hostmenu");
+    var hosts = [];
+    panels.forEach(function(p) {
+      if (!p.def.hosts) { return; }
+      (p.series || []).forEach(function(s) { if (hosts.indexOf(s.target) < 0) { hosts.push(s.target); } });
+    });
+    hosts.sort();
+    document.getElementById("hosts").style.display = hosts.length > 0 ? "" : "none";
+    while (menu.firstChild) { menu.removeChild(menu.firstChild); }
+    hosts.forEach(function(host) {
+      var label = el("label", {}, menu);
+      var cb = el("input", {type:"checkbox"}, label);
+      cb.checked = state.hosts[host] !== false;
+      cb.onchange = function() {
+        state.hosts[host] = cb.checked;
+        panels.forEach(function(p) { if (p.def.hosts) { draw(p); } });
+      };
+      label.appendChild(document.createTextNode(" " + host));
+      el("br", {}, menu);
+    });
+  }
+
+  function init(available) {
+    layout.forEach(function(def) {
+      var targets = def.targets.filter(function(t) { return available.indexOf(t) >= 0; });
+      if (targets.length > 0) { def.targets = targets; createPanel(def); }
+    });
+    buildMenus(available);
+    query(["host_info"], "table", function(data) {
+      if (data && data.length > 0) {
+        document.getElementById("hostinfo").textContent = data[0].rows.map(function(r) { return r[1]; }).slice(1, 4).join(" | ");
+      }
+    });
+    var probe = panels.map(function(p) { return p.def.targets[0]; });
+    query(probe, "timeserie", function(data) {
+      var x0 = Infinity, x1 = -Infinity;
+      (data || []).forEach(function(s) {
+        s.datapoints.forEach(function(dp) { x0 = Math.min(x0, dp[1]); x1 = Math.max(x1, dp[1]); });
+      });
+      if (isFinite(x0)) {
+        state.full = {from:new Date(x0), to:new Date(x1)};
+        state.from = state.full.from;
+        state.to = state.full.to;
+      }
+      refresh();
+      if (location.hash.length > 1) {
+        var target = document.getElementById(location.hash.substring(1));
+        if (target) { target.scrollIntoView(); }
+      }
+    });
+  }
+
+  document.getElementById("zoomout").onclick = zoomOut;
+  document.getElementById("downsample").onchange = refresh;
+  document.getElementById("reset").onclick = function() {
+    state.history = [];
+    state.from = state.full ? state.full.from : null;
+    state.to = state.full ? state.full.to : null;
+    refresh();
+  };
+  window.addEventListener("resize", function() { panels.forEach(draw); });
+  post("grafana/search", {target:""}, init);
+})();
+</script>
+</body>
+</html>
+`
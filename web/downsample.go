@@ -0,0 +1,145 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"math"
+)
+
+// downsampling algorithms
+const (
+	DownsampleAverage = "avg"
+	DownsampleLast    = "last"
+	DownsampleLTTB    = "lttb"
+	DownsampleMinMax  = "minmax"
+)
+
+// DefaultMaxDataPoints is used when a query doesn't ask for a number of data points
+const DefaultMaxDataPoints = 1000
+
+// Downsample reduces data points, [value, timestamp], to at most max points.
+// Unknown methods fall back to min/max, which keeps peaks and dips.
+func Downsample(dataPoints [][]float64, max int, method string) [][]float64 {
+	if max <= 0 {
+		max = DefaultMaxDataPoints
+	}
+	if len(dataPoints) <= max {
+		return dataPoints
+	}
+	switch method {
+	case DownsampleAverage:
+		return downsampleByBucket(dataPoints, max, averageOfBucket)
+	case DownsampleLast:
+		return downsampleByBucket(dataPoints, max, lastOfBucket)
+	case DownsampleLTTB:
+		return downsampleLTTB(dataPoints, max)
+	default:
+		return downsampleMinMax(dataPoints, max)
+	}
+}
+
+// getBuckets splits data points into n buckets of similar sizes
+func getBuckets(dataPoints [][]float64, n int) [][][]float64 {
+	buckets := make([][][]float64, 0, n)
+	size := float64(len(dataPoints)) / float64(n)
+	for i := 0; i < n; i++ {
+		b := int(float64(i) * size)
+		e := int(float64(i+1) * size)
+		if i == n-1 {
+			e = len(dataPoints)
+		}
+		if b < e {
+			buckets = append(buckets, dataPoints[b:e])
+		}
+	}
+	return buckets
+}
+
+func downsampleByBucket(dataPoints [][]float64, max int, f func([][]float64) []float64) [][]float64 {
+	points := make([][]float64, 0, max)
+	for _, bucket := range getBuckets(dataPoints, max) {
+		points = append(points, f(bucket))
+	}
+	return points
+}
+
+func averageOfBucket(bucket [][]float64) []float64 {
+	sum := float64(0)
+	for _, v := range bucket {
+		sum += v[0]
+	}
+	return []float64{sum / float64(len(bucket)), bucket[0][1]}
+}
+
+func lastOfBucket(bucket [][]float64) []float64 {
+	return []float64{bucket[len(bucket)-1][0], bucket[len(bucket)-1][1]}
+}
+
+// downsampleMinMax keeps the min and the max of each bucket in time order
+func downsampleMinMax(dataPoints [][]float64, max int) [][]float64 {
+	n := max / 2
+	if n < 1 {
+		n = 1
+	}
+	points := make([][]float64, 0, max)
+	for _, bucket := range getBuckets(dataPoints, n) {
+		lo, hi := 0, 0
+		for i, v := range bucket {
+			if v[0] < bucket[lo][0] {
+				lo = i
+			}
+			if v[0] > bucket[hi][0] {
+				hi = i
+			}
+		}
+		if lo == hi {
+			points = append(points, []float64{bucket[lo][0], bucket[lo][1]})
+		} else if lo < hi {
+			points = append(points, []float64{bucket[lo][0], bucket[lo][1]}, []float64{bucket[hi][0], bucket[hi][1]})
+		} else {
+			points = append(points, []float64{bucket[hi][0], bucket[hi][1]}, []float64{bucket[lo][0], bucket[lo][1]})
+		}
+	}
+	return points
+}
+
+// downsampleLTTB implements largest-triangle-three-buckets.  The first and
+// the last points are always kept.
+func downsampleLTTB(dataPoints [][]float64, max int) [][]float64 {
+	if max < 3 {
+		return downsampleMinMax(dataPoints, max)
+	}
+	last := len(dataPoints) - 1
+	buckets := getBuckets(dataPoints[1:last], max-2)
+	points := make([][]float64, 0, max)
+	points = append(points, []float64{dataPoints[0][0], dataPoints[0][1]})
+	a := dataPoints[0]
+	for i, bucket := range buckets {
+		// average point of the next bucket
+		var avgX, avgY float64
+		next := [][]float64{dataPoints[last]}
+		if i+1 < len(buckets) {
+			next = buckets[i+1]
+		}
+		for _, v := range next {
+			avgY += v[0]
+			avgX += v[1]
+		}
+		avgX /= float64(len(next))
+		avgY /= float64(len(next))
+
+		maxArea := float64(-1)
+		selected := bucket[0]
+		for _, v := range bucket {
+			area := math.Abs((a[1]-avgX)*(v[0]-a[0]) - (a[1]-v[1])*(avgY-a[0]))
+			if area > maxArea {
+				maxArea = area
+				selected = v
+			}
+		}
+		points = append(points, []float64{selected[0], selected[1]})
+		a = selected
+	}
+	points = append(points, []float64{dataPoints[last][0], dataPoints[last][1]})
+	return points
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func getTestDataPoints(n int, spike int) [][]float64 {
+	dps := [][]float64{}
+	for i := 0; i < n; i++ {
+		v := float64(10)
+		if i == spike {
+			v = 1000
+		} else if i == spike+1 {
+			v = 0
+		}
+		dps = append(dps, []float64{v, float64(i * 1000)})
+	}
+	return dps
+}
+
+func hasValue(dps [][]float64, v float64) bool {
+	for _, dp := range dps {
+		if dp[0] == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDownsample(t *testing.T) {
+	dps := getTestDataPoints(10000, 4321)
+	for _, method := range []string{DownsampleMinMax, DownsampleLTTB} {
+		points := Downsample(dps, 100, method)
+		if len(points) > 100 {
+			t.Fatal(method, len(points))
+		}
+		if hasValue(points, 1000) == false {
+			t.Fatal(method, "spike is lost")
+		}
+		for i := 1; i < len(points); i++ {
+			if points[i][1] <= points[i-1][1] {
+				t.Fatal(method, "timestamps out of order")
+			}
+		}
+	}
+	if points := Downsample(dps, 100, DownsampleMinMax); hasValue(points, 0) == false {
+		t.Fatal("dip is lost")
+	}
+	for _, method := range []string{DownsampleAverage, DownsampleLast} {
+		if points := Downsample(dps, 100, method); len(points) != 100 {
+			t.Fatal(method, len(points))
+		}
+	}
+	if points := Downsample(dps[:10], 100, DownsampleLTTB); len(points) != 10 {
+		t.Fatal(len(points))
+	}
+}
+
+func TestFilterTimeSeriesData(t *testing.T) {
+	dps := getTestDataPoints(5000, 100)
+	tsd := TimeSeriesDoc{Target: "test", DataPoints: dps}
+	data := filterTimeSeriesData(tsd, timeOf(0), timeOf(2999000), 0, "")
+	if len(data.DataPoints) > DefaultMaxDataPoints || hasValue(data.DataPoints, 1000) == false {
+		t.Fatal(len(data.DataPoints))
+	}
+	data = filterTimeSeriesData(tsd, timeOf(0), timeOf(2999000), 50, DownsampleLast)
+	if len(data.DataPoints) != 50 || dps[100][0] != 1000 {
+		t.Fatal(len(data.DataPoints))
+	}
+}
+
+func timeOf(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,262 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// builtInExpressions are derived metrics available by name
+var builtInExpressions = map[string]string{
+	"wt_cache_used_pct":          "wt_cache_used / wt_cache_max * 100",
+	"wt_cache_dirty_pct":         "wt_cache_dirty / wt_cache_max * 100",
+	"ticket_out_read_pct":        "ticket_out_read / ticket_total_read * 100",
+	"ticket_out_write_pct":       "ticket_out_write / ticket_total_write * 100",
+	"keys_examined_per_returned": "scan_keys / docs_returned",
+	"docs_examined_per_returned": "scan_objects / docs_returned",
+	"read_ms_per_op":             "oplat_reads_us / oplat_reads_ops / 1000",
+	"write_ms_per_op":            "oplat_writes_us / oplat_writes_ops / 1000",
+	"command_ms_per_op":          "oplat_commands_us / oplat_commands_ops / 1000",
+}
+
+// Expression is a parsed arithmetic expression of series, e.g. wt_cache_dirty / wt_cache_max * 100
+type Expression struct {
+	Expr   string
+	Series []string
+	root   exprNode
+}
+
+type exprNode interface {
+	eval(values map[string]float64) (float64, error)
+}
+
+type numberNode float64
+
+type seriesNode string
+
+type negateNode struct {
+	x exprNode
+}
+
+type binaryNode struct {
+	op   byte
+	x, y exprNode
+}
+
+var errDivideByZero = errors.New("divide by zero")
+
+func (n numberNode) eval(values map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (n seriesNode) eval(values map[string]float64) (float64, error) {
+	return values[string(n)], nil
+}
+
+func (n negateNode) eval(values map[string]float64) (float64, error) {
+	v, err := n.x.eval(values)
+	return -v, err
+}
+
+func (n binaryNode) eval(values map[string]float64) (float64, error) {
+	var err error
+	var x, y float64
+	if x, err = n.x.eval(values); err != nil {
+		return 0, err
+	}
+	if y, err = n.y.eval(values); err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return x + y, nil
+	case '-':
+		return x - y, nil
+	case '*':
+		return x * y, nil
+	default:
+		if y == 0 {
+			return 0, errDivideByZero
+		}
+		return x / y, nil
+	}
+}
+
+// exprParser is a recursive descent parser of
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = number | series | "(" expr ")" | "-" factor
+type exprParser struct {
+	tokens []string
+	pos    int
+	series map[string]bool
+}
+
+func tokenizeExpression(str string) ([]string, error) {
+	var tokens []string
+	r := []rune(str)
+	for i := 0; i < len(r); {
+		c := r[i]
+		if unicode.IsSpace(c) {
+			i++
+		} else if strings.ContainsRune("+-*/()", c) {
+			tokens = append(tokens, string(c))
+			i++
+		} else if unicode.IsDigit(c) || c == '.' {
+			j := i
+			for j < len(r) && (unicode.IsDigit(r[j]) || r[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		} else if unicode.IsLetter(c) || c == '_' {
+			j := i
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j]) || r[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, string(r[i:j]))
+			i = j
+		} else {
+			return tokens, fmt.Errorf("unexpected character '%c' at %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+// ParseExpression parses an expression of series names, numbers, + - * / and parentheses
+func ParseExpression(str string) (*Expression, error) {
+	var err error
+	var tokens []string
+	if tokens, err = tokenizeExpression(str); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty expression")
+	}
+	p := &exprParser{tokens: tokens, series: map[string]bool{}}
+	var root exprNode
+	if root, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token '%v'", p.tokens[p.pos])
+	}
+	e := &Expression{Expr: str, root: root}
+	for name := range p.series {
+		e.Series = append(e.Series, name)
+	}
+	sort.Strings(e.Series)
+	return e, nil
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) parseExpr() (exprNode, error) {
+	x, err := p.parseTerm()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.tokens[p.pos][0]
+		p.pos++
+		var y exprNode
+		if y, err = p.parseTerm(); err == nil {
+			x = binaryNode{op: op, x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) parseTerm() (exprNode, error) {
+	x, err := p.parseFactor()
+	for err == nil && (p.peek() == "*" || p.peek() == "/") {
+		op := p.tokens[p.pos][0]
+		p.pos++
+		var y exprNode
+		if y, err = p.parseFactor(); err == nil {
+			x = binaryNode{op: op, x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) parseFactor() (exprNode, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, errors.New("unexpected end of expression")
+	}
+	p.pos++
+	if token == "-" {
+		x, err := p.parseFactor()
+		return negateNode{x: x}, err
+	} else if token == "(" {
+		x, err := p.parseExpr()
+		if err != nil {
+			return x, err
+		}
+		if p.peek() != ")" {
+			return x, errors.New("missing ')'")
+		}
+		p.pos++
+		return x, nil
+	} else if unicode.IsDigit(rune(token[0])) || token[0] == '.' {
+		v, err := strconv.ParseFloat(token, 64)
+		return numberNode(v), err
+	} else if unicode.IsLetter(rune(token[0])) || token[0] == '_' {
+		p.series[token] = true
+		return seriesNode(token), nil
+	}
+	return nil, fmt.Errorf("unexpected token '%v'", token)
+}
+
+// Evaluate evaluates an expression over data points with aligned timestamps.
+// Timestamps missing from any referenced series and divisions by zero are skipped.
+func (e *Expression) Evaluate(tsData map[string]TimeSeriesDoc) (TimeSeriesDoc, error) {
+	data := TimeSeriesDoc{Target: e.Expr, DataPoints: [][]float64{}}
+	if len(e.Series) == 0 {
+		return data, errors.New("expression references no series")
+	}
+	valuesMap := map[string]map[float64]float64{}
+	for _, name := range e.Series {
+		doc, ok := tsData[name]
+		if ok == false {
+			return data, fmt.Errorf("series %v not found", name)
+		}
+		values := map[float64]float64{}
+		for _, dp := range doc.DataPoints {
+			values[dp[1]] = dp[0]
+		}
+		valuesMap[name] = values
+	}
+	for _, dp := range tsData[e.Series[0]].DataPoints {
+		t := dp[1]
+		values := map[string]float64{}
+		isAligned := true
+		for _, name := range e.Series {
+			if v, ok := valuesMap[name][t]; ok {
+				values[name] = v
+			} else {
+				isAligned = false
+				break
+			}
+		}
+		if isAligned == false {
+			continue
+		}
+		v, err := e.root.eval(values)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		data.DataPoints = append(data.DataPoints, []float64{v, t})
+	}
+	return data, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	e, err := ParseExpression("wt_cache_dirty / wt_cache_max * 100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Series) != 2 || e.Series[0] != "wt_cache_dirty" || e.Series[1] != "wt_cache_max" {
+		t.Fatal(e.Series)
+	}
+	for _, expr := range []string{"", "a +", "(a * 2", "a $ b", "a b"} {
+		if _, err = ParseExpression(expr); err == nil {
+			t.Fatal("expected error:", expr)
+		}
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	tsData := map[string]TimeSeriesDoc{
+		"a": TimeSeriesDoc{"a", [][]float64{{1, 1000}, {2, 2000}, {3, 3000}, {4, 4000}}},
+		"b": TimeSeriesDoc{"b", [][]float64{{4, 1000}, {0, 2000}, {8, 4000}}},
+	}
+	e, err := ParseExpression("-(a + 1) / b * 100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := e.Evaluate(tsData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2000 is divided by zero and 3000 is missing from b
+	if len(data.DataPoints) != 2 || data.DataPoints[0][0] != -50 || data.DataPoints[1][1] != 4000 || data.DataPoints[1][0] != -62.5 {
+		t.Fatal(data.DataPoints)
+	}
+	if e, err = ParseExpression("a / c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.Evaluate(tsData); err == nil {
+		t.Fatal("expected series not found")
+	}
+}
+
+func TestQueryUnknownTarget(t *testing.T) {
+	grafana := NewGrafana()
+	grafana.detailFTDC.timeSeriesData = map[string]TimeSeriesDoc{
+		"wt_cache_max": TimeSeriesDoc{"wt_cache_max", [][]float64{{8, 60000}, {8, 120000}}},
+	}
+	w := httptest.NewRecorder()
+	body := `{"range":{"from":"1970-01-01T00:00:00Z","to":"1970-01-01T01:00:00Z"},"targets":[{"target":"wt_cache_used / 2","type":"timeserie"}]}`
+	grafana.handler(w, httptest.NewRequest(http.MethodPost, "/grafana/query", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "wt_cache_used") == false {
+		t.Fatal(w.Code, w.Body.String())
+	}
+}
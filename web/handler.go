@@ -5,8 +5,8 @@ package web
 import (
 	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
@@ -23,9 +23,72 @@ func (g *Grafana) handler(w http.ResponseWriter, r *http.Request) {
 		g.search(w, r)
 	} else if r.URL.Path[1:] == "grafana/dir" {
 		g.readDirectory(w, r)
+	} else if r.URL.Path[1:] == "grafana/expressions" {
+		g.expressionsHandler(w, r)
 	}
 }
 
+type expressionReq struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+// expressionsHandler lists (GET) or saves (POST) named expressions, an empty expr removes one
+func (g *Grafana) expressionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+	case http.MethodGet:
+		g.RLock()
+		defer g.RUnlock()
+		json.NewEncoder(w).Encode(g.expressions)
+	case http.MethodPost:
+		var err error
+		decoder := json.NewDecoder(r.Body)
+		var er expressionReq
+		if err = decoder.Decode(&er); err != nil {
+			json.NewEncoder(w).Encode(bson.M{"ok": 0, "err": err.Error()})
+			return
+		}
+		g.Lock()
+		defer g.Unlock()
+		if _, ok := g.detailFTDC.timeSeriesData[er.Name]; ok || er.Name == "" {
+			json.NewEncoder(w).Encode(bson.M{"ok": 0, "err": "invalid name '" + er.Name + "'"})
+			return
+		}
+		if er.Expr == "" {
+			delete(g.expressions, er.Name)
+			json.NewEncoder(w).Encode(bson.M{"ok": 1, "name": er.Name})
+			return
+		}
+		if _, err = ParseExpression(er.Expr); err != nil {
+			json.NewEncoder(w).Encode(bson.M{"ok": 0, "err": err.Error()})
+			return
+		}
+		g.expressions[er.Name] = er.Expr
+		json.NewEncoder(w).Encode(bson.M{"ok": 1, "name": er.Name, "expr": er.Expr})
+	default:
+		http.Error(w, "bad method; supported OPTIONS, GET, POST", http.StatusBadRequest)
+		return
+	}
+}
+
+// evaluate evaluates a named expression or an expression target
+func (g *Grafana) evaluate(ftdc FTDCStats, target string) (TimeSeriesDoc, error) {
+	g.RLock()
+	expr, ok := g.expressions[target]
+	g.RUnlock()
+	if ok == false {
+		expr = target
+	}
+	e, err := ParseExpression(expr)
+	if err != nil {
+		return TimeSeriesDoc{Target: target, DataPoints: [][]float64{}}, err
+	}
+	data, err := e.Evaluate(ftdc.timeSeriesData)
+	data.Target = target
+	return data, err
+}
+
 type directoryReq struct {
 	Dir  string `json:"dir"`
 	Span int    `json:"span"`
@@ -70,6 +133,15 @@ func (g *Grafana) search(w http.ResponseWriter, r *http.Request) {
 		list = append(list, doc.Target)
 	}
 
+	g.RLock()
+	names := []string{}
+	for name := range g.expressions {
+		names = append(names, name)
+	}
+	g.RUnlock()
+	sort.Strings(names)
+	list = append(list, names...)
+	list = append(list, g.getSlowOpsTargets()...)
 	list = append(list, "host_info")
 	json.NewEncoder(w).Encode(list)
 }
@@ -87,27 +159,18 @@ func (g *Grafana) query(w http.ResponseWriter, r *http.Request) {
 	}
 	var tsData []interface{}
 	for _, target := range qr.Targets {
+		method := target.Data.Downsample
+		if method == "" {
+			method = qr.Downsample
+		}
 		if target.Type == "timeserie" {
-			if target.Target == "replication_lags" { // replaced with actual hostname
-				for k, v := range ftdc.replicationLags {
-					data := v
-					data.Target = k
-					tsData = append(tsData, filterTimeSeriesData(data, qr.Range.From, qr.Range.To))
-				}
-			} else if target.Target == "disks_utils" {
-				for k, v := range ftdc.diskStats {
-					data := v.utilization
-					data.Target = k
-					tsData = append(tsData, filterTimeSeriesData(data, qr.Range.From, qr.Range.To))
-				}
-			} else if target.Target == "disks_iops" {
-				for k, v := range ftdc.diskStats {
-					data := v.iops
-					data.Target = k
-					tsData = append(tsData, filterTimeSeriesData(data, qr.Range.From, qr.Range.To))
-				}
-			} else {
-				tsData = append(tsData, filterTimeSeriesData(ftdc.timeSeriesData[target.Target], qr.Range.From, qr.Range.To))
+			list, err := g.getTimeSeries(ftdc, target.Target)
+			if err != nil { // shown by Grafana as the query error
+				http.Error(w, target.Target+": "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, data := range list {
+				tsData = append(tsData, filterTimeSeriesData(data, qr.Range.From, qr.Range.To, qr.MaxDataPoints, method))
 			}
 		} else if target.Type == "table" {
 			if target.Target == "host_info" {
@@ -138,7 +201,49 @@ func (g *Grafana) query(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tsData)
 }
 
-func filterTimeSeriesData(tsData TimeSeriesDoc, from time.Time, to time.Time) TimeSeriesDoc {
+// getTimeSeries returns series of a target, replication_lags and disks_* expand to one per host or disk, and errors
+// of invalid expressions or unknown targets
+func (g *Grafana) getTimeSeries(ftdc FTDCStats, target string) ([]TimeSeriesDoc, error) {
+	var list []TimeSeriesDoc
+	if target == "replication_lags" { // replaced with actual hostname
+		for k, v := range ftdc.replicationLags {
+			data := v
+			data.Target = k
+			list = append(list, data)
+		}
+	} else if target == "disks_utils" {
+		for k, v := range ftdc.diskStats {
+			data := v.utilization
+			data.Target = k
+			list = append(list, data)
+		}
+	} else if target == "disks_iops" {
+		for k, v := range ftdc.diskStats {
+			data := v.iops
+			data.Target = k
+			list = append(list, data)
+		}
+	} else if data, ok := ftdc.timeSeriesData[target]; ok {
+		list = append(list, data)
+	} else if series, ok := g.getSlowOpsTimeSeries(target); ok {
+		list = append(list, series...)
+	} else {
+		data, err := g.evaluate(ftdc, target)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, data)
+	}
+	return list, nil
+}
+
+func filterTimeSeriesData(tsData TimeSeriesDoc, from time.Time, to time.Time, maxDataPoints int, method string) TimeSeriesDoc {
+	data := filterByTimeRange(tsData, from, to)
+	data.DataPoints = Downsample(data.DataPoints, maxDataPoints, method)
+	return data
+}
+
+func filterByTimeRange(tsData TimeSeriesDoc, from time.Time, to time.Time) TimeSeriesDoc {
 	var data = TimeSeriesDoc{DataPoints: [][]float64{}}
 	data.Target = tsData.Target
 	for _, v := range tsData.DataPoints {
@@ -148,29 +253,5 @@ func filterTimeSeriesData(tsData TimeSeriesDoc, from time.Time, to time.Time) Ti
 		}
 		data.DataPoints = append(data.DataPoints, v)
 	}
-
-	max := 1000 // max data points, 5 minutes * 1000 = 84 hours
-	if len(data.DataPoints) > max {
-		frac := len(data.DataPoints) / max
-		var datax = TimeSeriesDoc{DataPoints: [][]float64{}}
-		datax.Target = tsData.Target
-		count := 0
-		sum := float64(0)
-		last := len(data.DataPoints) - 1
-		for i, v := range data.DataPoints {
-			if v[0] > 0 || count == 0 { // has value or the first value
-				sum += v[0]
-				count++
-			}
-			if i%frac != 0 && i != last {
-				continue
-			}
-			v[0] = math.Round(sum / float64(count))
-			datax.DataPoints = append(datax.DataPoints, v)
-			count = 0
-			sum = 0
-		}
-		return datax
-	}
 	return data
 }
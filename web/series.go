@@ -0,0 +1,220 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyCharts are the former per-chart pages and their /v1/<chart>/tsv series
+var legacyCharts = map[string][]string{
+	"memory":             []string{"mem_resident", "mem_virtual"},
+	"page_faults":        []string{"mem_page_faults"},
+	"metrics":            []string{"scan_keys", "scan_objects", "scan_sort", "docs_returned"},
+	"wiredtiger_cache":   []string{"wt_cache_max", "wt_cache_used", "wt_cache_dirty"},
+	"ops":                []string{"ops_query", "ops_insert", "ops_update", "ops_delete", "ops_getmore", "ops_command"},
+	"wiredtiger_tickets": []string{"ticket_avail_read", "ticket_avail_write"},
+	"wiredtiger_paging":  []string{"wt_modified_evicted", "wt_unmodified_evicted", "wt_read_in_cache", "wt_written_from_cache"},
+	"latencies":          []string{"latency_read", "latency_write", "latency_command"},
+	"connections":        []string{"conns_current", "conns_available", "conns_created_per_minute"},
+	"queues":             []string{"q_active_read", "q_active_write", "q_queued_read", "q_queued_write"},
+	"repl_lags":          []string{"replication_lags"},
+}
+
+// SeriesRequest is a request of /v1/series
+type SeriesRequest struct {
+	Names  []string
+	From   time.Time
+	To     time.Time
+	Step   time.Duration
+	Format string
+}
+
+// seriesHandler serves /v1/series and /v1/series/list
+func (g *Grafana) seriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "bad method; supported GET", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path[1:] == "v1/series/list" {
+		json.NewEncoder(w).Encode(g.listSeries())
+		return
+	}
+	sr, err := parseSeriesRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	g.writeSeries(w, sr)
+}
+
+// writeSeries writes series in csv, tsv or json format
+func (g *Grafana) writeSeries(w http.ResponseWriter, sr SeriesRequest) {
+	list, err := g.getSeries(sr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch sr.Format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		fmt.Fprint(w, strings.Join(getSeriesTable(list, ","), "\n"))
+	default:
+		w.Header().Set("Content-Type", "text/tab-separated-values")
+		fmt.Fprint(w, strings.Join(getSeriesTable(list, "\t"), "\n"))
+	}
+}
+
+func parseSeriesRequest(r *http.Request) (SeriesRequest, error) {
+	var err error
+	values := r.URL.Query()
+	sr := SeriesRequest{Format: values.Get("format"), To: time.Now()}
+	for _, name := range strings.Split(values.Get("names"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sr.Names = append(sr.Names, name)
+		}
+	}
+	if len(sr.Names) == 0 {
+		return sr, errors.New("names is required")
+	}
+	if sr.Format == "" {
+		sr.Format = "tsv"
+	} else if sr.Format != "csv" && sr.Format != "json" && sr.Format != "tsv" {
+		return sr, errors.New("format must be csv, json or tsv")
+	}
+	if v := values.Get("from"); v != "" {
+		if sr.From, err = parseSeriesTime(v); err != nil {
+			return sr, err
+		}
+	}
+	if v := values.Get("to"); v != "" {
+		if sr.To, err = parseSeriesTime(v); err != nil {
+			return sr, err
+		}
+	}
+	if v := values.Get("step"); v != "" {
+		if n, e := strconv.Atoi(v); e == nil {
+			sr.Step = time.Duration(n) * time.Second
+		} else if sr.Step, err = time.ParseDuration(v); err != nil {
+			return sr, errors.New("step must be seconds or a duration, e.g. 5m")
+		}
+		if sr.Step < time.Second {
+			return sr, errors.New("step must be at least 1 second")
+		}
+	}
+	return sr, nil
+}
+
+// parseSeriesTime accepts RFC3339 or epoch milliseconds
+func parseSeriesTime(str string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	return time.Parse(time.RFC3339, str)
+}
+
+// listSeries lists names of all series and named expressions
+func (g *Grafana) listSeries() []string {
+	list := []string{}
+	ftdc := g.getSeriesFTDC()
+	for name := range ftdc.timeSeriesData {
+		list = append(list, name)
+	}
+	for _, name := range []string{"replication_lags", "disks_utils", "disks_iops"} {
+		list = append(list, name)
+	}
+	g.RLock()
+	for name := range g.expressions {
+		list = append(list, name)
+	}
+	g.RUnlock()
+	list = append(list, g.getSlowOpsTargets()...)
+	sort.Strings(list)
+	return list
+}
+
+// getSeriesFTDC returns detail stats if loaded, otherwise the summary
+func (g *Grafana) getSeriesFTDC() FTDCStats {
+	if len(g.detailFTDC.timeSeriesData) > 0 {
+		return g.detailFTDC
+	}
+	return g.summaryFTDC
+}
+
+func (g *Grafana) getSeries(sr SeriesRequest) ([]TimeSeriesDoc, error) {
+	list := []TimeSeriesDoc{}
+	ftdc := g.getSeriesFTDC()
+	for _, name := range sr.Names {
+		series, err := g.getTimeSeries(ftdc, name)
+		if err != nil {
+			return list, fmt.Errorf("%s: %v", name, err)
+		}
+		sort.Slice(series, func(i, j int) bool { return series[i].Target < series[j].Target })
+		for _, data := range series {
+			if len(series) > 1 {
+				data.Target = name + "/" + data.Target
+			}
+			data = filterByTimeRange(data, sr.From, sr.To)
+			if sr.Step > 0 {
+				data.DataPoints = resample(data.DataPoints, sr.Step)
+			}
+			list = append(list, data)
+		}
+	}
+	return list, nil
+}
+
+// resample averages data points by step, timestamps are the beginnings of steps
+func resample(dataPoints [][]float64, step time.Duration) [][]float64 {
+	points := [][]float64{}
+	ms := float64(step / time.Millisecond)
+	count := 0
+	for _, v := range dataPoints {
+		t := float64(int64(v[1]/ms)) * ms
+		if count > 0 && points[len(points)-1][1] == t {
+			last := points[len(points)-1]
+			last[0] = (last[0]*float64(count) + v[0]) / float64(count+1)
+			count++
+			continue
+		}
+		points = append(points, []float64{v[0], t})
+		count = 1
+	}
+	return points
+}
+
+// getSeriesTable joins series by timestamps, missing values are left empty
+func getSeriesTable(list []TimeSeriesDoc, sep string) []string {
+	header := []string{"date"}
+	rows := map[float64][]string{}
+	for i, data := range list {
+		header = append(header, data.Target)
+		for _, v := range data.DataPoints {
+			if _, ok := rows[v[1]]; !ok {
+				rows[v[1]] = make([]string, len(list))
+			}
+			rows[v[1]][i] = strconv.FormatFloat(v[0], 'f', -1, 64)
+		}
+	}
+	timestamps := []float64{}
+	for t := range rows {
+		timestamps = append(timestamps, t)
+	}
+	sort.Float64s(timestamps)
+	lines := []string{strings.Join(header, sep)}
+	for _, t := range timestamps {
+		tm := time.Unix(0, int64(t)*int64(time.Millisecond)).UTC()
+		lines = append(lines, tm.Format("2006-01-02T15:04:05Z")+sep+strings.Join(rows[t], sep))
+	}
+	return lines
+}
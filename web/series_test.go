@@ -0,0 +1,77 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getTestGrafana() *Grafana {
+	grafana := NewGrafana()
+	grafana.detailFTDC.timeSeriesData = map[string]TimeSeriesDoc{
+		"wt_cache_max":   TimeSeriesDoc{"wt_cache_max", [][]float64{{8, 60000}, {8, 120000}, {8, 180000}}},
+		"wt_cache_dirty": TimeSeriesDoc{"wt_cache_dirty", [][]float64{{1, 60000}, {2, 120000}, {4, 180000}}},
+	}
+	return grafana
+}
+
+func TestSeriesHandler(t *testing.T) {
+	grafana := getTestGrafana()
+	w := httptest.NewRecorder()
+	url := "/v1/series?names=wt_cache_dirty,wt_cache_dirty%20/%20wt_cache_max%20*%20100&format=csv&from=0&to=150000"
+	grafana.seriesHandler(w, httptest.NewRequest(http.MethodGet, url, nil))
+	lines := strings.Split(w.Body.String(), "\n")
+	if len(lines) != 3 || lines[0] != "date,wt_cache_dirty,wt_cache_dirty / wt_cache_max * 100" || lines[2] != "1970-01-01T00:02:00Z,2,25" {
+		t.Fatal(lines)
+	}
+
+	w = httptest.NewRecorder()
+	grafana.seriesHandler(w, httptest.NewRequest(http.MethodGet, "/v1/series?names=wt_cache_dirty&format=json&step=5m", nil))
+	var list []TimeSeriesDoc
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || len(list[0].DataPoints) != 1 || list[0].DataPoints[0][0] != float64(7)/3 {
+		t.Fatal(list)
+	}
+
+	w = httptest.NewRecorder()
+	grafana.seriesHandler(w, httptest.NewRequest(http.MethodGet, "/v1/series?names=wt_cache_dirty&format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatal(w.Code)
+	}
+
+	for _, step := range []string{"500us", "0", "-5"} {
+		w = httptest.NewRecorder()
+		grafana.seriesHandler(w, httptest.NewRequest(http.MethodGet, "/v1/series?names=wt_cache_dirty&step="+step, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatal(step, w.Code)
+		}
+	}
+}
+
+func TestListSeries(t *testing.T) {
+	grafana := getTestGrafana()
+	w := httptest.NewRecorder()
+	grafana.seriesHandler(w, httptest.NewRequest(http.MethodGet, "/v1/series/list", nil))
+	var list []string
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(strings.Join(list, ","), "wt_cache_dirty_pct") < 0 {
+		t.Fatal(list)
+	}
+}
+
+func TestResample(t *testing.T) {
+	dps := [][]float64{{1, 1000}, {3, 59000}, {5, 61000}}
+	points := resample(dps, time.Minute)
+	if len(points) != 2 || points[0][0] != 2 || points[1][1] != 60000 {
+		t.Fatal(points)
+	}
+}
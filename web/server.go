@@ -3,400 +3,195 @@
 package web
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
-
-	"github.com/simagix/keyhole/mdb"
-	"github.com/simagix/keyhole/sim"
+	"time"
 )
 
-func handler(w http.ResponseWriter, r *http.Request) {
-	var str string
-	if r.URL.Path[1:] == "memory" || r.URL.Path[1:] == "" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Memory (GB)", -1)
-		str = strings.Replace(str, "__API__", "v1/memory/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/memory/tsv" {
-		str = strings.Join(GetMemoryTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "page_faults" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Page Faults", -1)
-		str = strings.Replace(str, "__API__", "v1/page_faults/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/page_faults/tsv" {
-		str = strings.Join(GetPageFaultsTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "metrics" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Metrics", -1)
-		str = strings.Replace(str, "__API__", "v1/metrics/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/metrics/tsv" {
-		str = strings.Join(GetMetricsTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "wiredtiger_cache" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "WiredTiger Cache (GB)", -1)
-		str = strings.Replace(str, "__API__", "v1/wiredtiger_cache/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/wiredtiger_cache/tsv" {
-		str = strings.Join(GetWiredTigerCacheTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "ops" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Ops Counters", -1)
-		str = strings.Replace(str, "__API__", "v1/ops/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/ops/tsv" {
-		str = strings.Join(GetOpCountersTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "wiredtiger_tickets" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "WiredTiger Tickets", -1)
-		str = strings.Replace(str, "__API__", "v1/wiredtiger_tickets/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/wiredtiger_tickets/tsv" {
-		str = strings.Join(GetWiredTigerTicketsTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "wiredtiger_paging" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "WiredTiger Paging (pages per minute)", -1)
-		str = strings.Replace(str, "__API__", "v1/wiredtiger_paging/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/wiredtiger_paging/tsv" {
-		fmt.Fprintf(w, strings.Join(GetWiredTigerPagingTSV()[:], "\n"))
-
-	} else if r.URL.Path[1:] == "latencies" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Latencies (milliseconds)", -1)
-		str = strings.Replace(str, "__API__", "v1/latencies/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/latencies/tsv" {
-		str = strings.Join(GetLatenciesTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "connections" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Connections", -1)
-		str = strings.Replace(str, "__API__", "v1/connections/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/connections/tsv" {
-		str = strings.Join(GetConnectionsTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "queues" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Queues", -1)
-		str = strings.Replace(str, "__API__", "v1/queues/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/queues/tsv" {
-		str = strings.Join(GetQueuesTSV()[:], "\n")
-
-	} else if r.URL.Path[1:] == "repl_lags" {
-		str = strings.Replace(IndexHTML, "__TITLE__", "Replication Lags (seconds)", -1)
-		str = strings.Replace(str, "__API__", "v1/repl_lags/tsv", -1)
-	} else if r.URL.Path[1:] == "v1/repl_lags/tsv" {
-		str = strings.Join(GetReplLagsTSV()[:], "\n")
-
-	} else {
-		str = "Keyhole Performance Charts!  Unknow API!"
-	}
-	fmt.Fprintf(w, str)
+// Server serves the dashboard, Grafana datasource and series APIs
+type Server struct {
+	adminAuth string
+	auth      string
+	bind      string
+	grafana   *Grafana
+	origins   []string
+	port      int
+	tlsCert   string
+	tlsKey    string
 }
 
-func cors(f http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Headers", "accept, content-type")
-		w.Header().Set("Access-Control-Allow-Methods", "POST")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		f(w, r)
-	}
+// NewServer returns a server listening to localhost:5408 by default
+func NewServer(g *Grafana) *Server {
+	return &Server{bind: "localhost", grafana: g, port: 5408}
 }
 
-var diagnosticData sim.DiagnosticData
-var base = 144
-var frac int
-
-// HTTPServer listens to port 5408
-func HTTPServer(port int, d *sim.DiagnosticData, g *Grafana) {
-	var err error
-	diagnosticData = *d
-	http.HandleFunc("/grafana", cors(g.handler))
-	http.HandleFunc("/grafana/", cors(g.handler))
-	http.HandleFunc("/", cors(handler))
-	var hostname string
-	if hostname, err = os.Hostname(); err != nil {
-		hostname = "localhost"
-	}
-	log.Println("HTTP server ready, URL: http://" + hostname + ":5408/")
-	log.Fatal(http.ListenAndServe(":"+strconv.Itoa(port), nil))
+// SetAdminAuth sets credentials of mutating endpoints, user:password for basic auth or a token
+func (s *Server) SetAdminAuth(auth string) {
+	s.adminAuth = auth
 }
 
-// GetMemoryTSV -
-func GetMemoryTSV() []string {
-	var docs []string
-	var r, v float64
-	docs = append(docs, "date\tResident\tVirtual")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		r = float64(stat.Mem.Resident) / 1024
-		v = float64(stat.Mem.Virtual) / 1024
-		docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-			"\t"+strconv.FormatFloat(r, 'E', -1, 64)+
-			"\t"+strconv.FormatFloat(v, 'E', -1, 64))
-	}
-
-	return docs
+// SetAuth sets credentials of read-only endpoints, user:password for basic auth or a token
+func (s *Server) SetAuth(auth string) {
+	s.auth = auth
 }
 
-// GetPageFaultsTSV -
-func GetPageFaultsTSV() []string {
-	var docs []string
-	pstat := mdb.ServerStatusDoc{}
-	docs = append(docs, "date\tPage Faults")
-
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		if i > 0 && stat.Uptime > pstat.Uptime {
-			n := stat.ExtraInfo.PageFaults - pstat.ExtraInfo.PageFaults
-			docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+"\t"+strconv.FormatInt(n, 10))
-		}
-		pstat = stat
-	}
-
-	return docs
+// SetBind sets bind address
+func (s *Server) SetBind(bind string) {
+	s.bind = bind
 }
 
-// GetMetricsTSV -
-func GetMetricsTSV() []string {
-	var docs []string
-	var s1, s2, s3 int64
-	var d, ii, r, u int
-	var pstat mdb.ServerStatusDoc
-
-	docs = append(docs, "date\tScanned Keys\tScanned Objects\tScan And Order\tDeleted\tInserted\tReturned\tUpdated")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		if stat.Uptime > pstat.Uptime {
-			s1 = stat.Metrics.QueryExecutor.Scanned - pstat.Metrics.QueryExecutor.Scanned
-			s2 = stat.Metrics.QueryExecutor.ScannedObjects - pstat.Metrics.QueryExecutor.ScannedObjects
-			s3 = stat.Metrics.Operation.ScanAndOrder - pstat.Metrics.Operation.ScanAndOrder
-			d = stat.Metrics.Document.Deleted - pstat.Metrics.Document.Deleted
-			ii = stat.Metrics.Document.Inserted - pstat.Metrics.Document.Inserted
-			r = stat.Metrics.Document.Returned - pstat.Metrics.Document.Returned
-			u = stat.Metrics.Document.Updated - pstat.Metrics.Document.Updated
-			docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-				"\t"+strconv.FormatInt(s1, 10)+"\t"+strconv.FormatInt(s2, 10)+"\t"+strconv.FormatInt(s3, 10)+
-				"\t"+strconv.Itoa(d)+"\t"+strconv.Itoa(ii)+"\t"+strconv.Itoa(r)+"\t"+strconv.Itoa(u))
+// SetOrigins sets allowed CORS origins, comma separated
+func (s *Server) SetOrigins(origins string) {
+	s.origins = nil
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			s.origins = append(s.origins, origin)
 		}
-		pstat = stat
 	}
+}
 
-	return docs
+// SetPort sets port number
+func (s *Server) SetPort(port int) {
+	s.port = port
 }
 
-// GetWiredTigerCacheTSV -
-func GetWiredTigerCacheTSV() []string {
-	var docs []string
-	var m, c, t float64
+// SetTLS sets certificate and key files
+func (s *Server) SetTLS(certFile string, keyFile string) {
+	s.tlsCert = certFile
+	s.tlsKey = keyFile
+}
 
-	docs = append(docs, "date\tMax Bytes\tIn Cache\tDirty Bytes")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
+func handler(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path[1:]
+	if path == "" || path == "dashboard" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, DashboardHTML)
+		return
+	}
+	for chart, names := range legacyCharts {
+		if path == chart { // former single-chart pages are panels of the dashboard
+			http.Redirect(w, r, "/#"+chart, http.StatusMovedPermanently)
+			return
+		} else if path == "v1/"+chart+"/tsv" {
+			g.writeSeries(w, SeriesRequest{Names: names, To: time.Now(), Format: "tsv"})
+			return
 		}
-		m = float64(stat.WiredTiger.Cache.MaxBytesConfigured) / (1024 * 1024 * 1024)
-		c = float64(stat.WiredTiger.Cache.CurrentlyInCache) / (1024 * 1024 * 1024)
-		t = float64(stat.WiredTiger.Cache.TrackedDirtyBytes) / (1024 * 1024 * 1024)
-		docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-			"\t"+strconv.FormatFloat(m, 'E', -1, 64)+
-			"\t"+strconv.FormatFloat(c, 'E', -1, 64)+
-			"\t"+strconv.FormatFloat(t, 'E', -1, 64))
 	}
+	fmt.Fprint(w, "Keyhole Performance Charts!  Unknow API!")
+}
 
-	return docs
+// isMutating returns true for endpoints that change the loaded data
+func isMutating(r *http.Request) bool {
+	path := r.URL.Path[1:]
+	return path == "grafana/dir" || (path == "grafana/expressions" && r.Method == http.MethodPost)
 }
 
-// GetWiredTigerPagingTSV -
-func GetWiredTigerPagingTSV() []string {
-	var docs []string
-	var pstat mdb.ServerStatusDoc
-	var m, u, r, w float64
+// tokenCookie keeps a token of the dashboard, browsers send it with requests of the dashboard
+const tokenCookie = "keyhole_token"
 
-	docs = append(docs, "date\tModified Evicted\tUnmodified Evicted\tRead In Cache\tWritten From Cache")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		if i > 0 && stat.Uptime > pstat.Uptime {
-			minutes := stat.LocalTime.Sub(pstat.LocalTime).Minutes()
-			m = float64(stat.WiredTiger.Cache.ModifiedPagesEvicted-pstat.WiredTiger.Cache.ModifiedPagesEvicted) / minutes
-			u = float64(stat.WiredTiger.Cache.UnmodifiedPagesEvicted-pstat.WiredTiger.Cache.UnmodifiedPagesEvicted) / minutes
-			r = float64(stat.WiredTiger.Cache.PagesReadIntoCache-pstat.WiredTiger.Cache.PagesReadIntoCache) / minutes
-			w = float64(stat.WiredTiger.Cache.PagesWrittenFromCache-pstat.WiredTiger.Cache.PagesWrittenFromCache) / minutes
-			docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-				"\t"+strconv.FormatFloat(m, 'E', -1, 64)+
-				"\t"+strconv.FormatFloat(u, 'E', -1, 64)+
-				"\t"+strconv.FormatFloat(r, 'E', -1, 64)+
-				"\t"+strconv.FormatFloat(w, 'E', -1, 64))
-		}
-		pstat = stat
+// isAuthorized checks basic auth if credentials are user:password, otherwise a token
+func isAuthorized(r *http.Request, auth string) bool {
+	if auth == "" {
+		return true
 	}
-
-	return docs
-}
-
-// GetWiredTigerTicketsTSV -
-func GetWiredTigerTicketsTSV() []string {
-	var docs []string
-	docs = append(docs, "date\tRead Ticket Available\tWrite Ticket Available")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-			"\t"+strconv.FormatInt(stat.WiredTiger.ConcurrentTransactions.Read.Available, 10)+
-			"\t"+strconv.FormatInt(stat.WiredTiger.ConcurrentTransactions.Write.Available, 10))
+	if strings.Index(auth, ":") > 0 {
+		user, password, ok := r.BasicAuth()
+		return ok && subtle.ConstantTimeCompare([]byte(user+":"+password), []byte(auth)) == 1
 	}
-
-	return docs
+	return subtle.ConstantTimeCompare([]byte(getToken(r)), []byte(auth)) == 1
 }
 
-// GetOpCountersTSV -
-func GetOpCountersTSV() []string {
-	var docs []string
-	pstat := mdb.ServerStatusDoc{}
-	docs = append(docs, "date\tQuery\tInsert\tUpdate\tDelete\tGet More\tCommand")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		if i > 0 && stat.Uptime > pstat.Uptime {
-			qry := stat.OpCounters.Query - pstat.OpCounters.Query
-			ins := stat.OpCounters.Insert - pstat.OpCounters.Insert
-			upt := stat.OpCounters.Update - pstat.OpCounters.Update
-			del := stat.OpCounters.Delete - pstat.OpCounters.Delete
-			gm := stat.OpCounters.Getmore - pstat.OpCounters.Getmore
-			cmd := stat.OpCounters.Command - pstat.OpCounters.Command
-
-			docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+"\t"+
-				strconv.FormatInt(qry, 10)+"\t"+strconv.FormatInt(ins, 10)+"\t"+strconv.FormatInt(upt, 10)+"\t"+
-				strconv.FormatInt(del, 10)+"\t"+strconv.FormatInt(gm, 10)+"\t"+strconv.FormatInt(cmd, 10))
-		}
-		pstat = stat
+// getToken returns a bearer token, a token query parameter, e.g. /?token=<token> of the dashboard, or the token
+// cookie
+func getToken(r *http.Request) string {
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		return token
+	} else if token = r.URL.Query().Get("token"); token != "" {
+		return token
+	} else if cookie, err := r.Cookie(tokenCookie); err == nil {
+		return cookie.Value
 	}
-
-	return docs
+	return ""
 }
 
-// GetLatenciesTSV -
-func GetLatenciesTSV() []string {
-	var docs []string
-	var r, w, c float64
-
-	docs = append(docs, "date\tReads (ms)\tWrites (ms)\tCommands (ms)")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		r = 0
-		if stat.OpLatencies.Reads.Ops > 0 {
-			r = float64(stat.OpLatencies.Reads.Latency) / float64(stat.OpLatencies.Reads.Ops) / 1000
+// secure sets CORS headers of allowed origins and checks credentials
+func (s *Server) secure(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			for _, o := range s.origins {
+				if o == "*" || o == origin {
+					w.Header().Set("Access-Control-Allow-Headers", "accept, authorization, content-type")
+					w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Vary", "Origin")
+					break
+				}
+			}
 		}
-		w = 0
-		if stat.OpLatencies.Writes.Ops > 0 {
-			w = float64(stat.OpLatencies.Writes.Latency) / float64(stat.OpLatencies.Writes.Ops) / 1000
+		if r.Method == http.MethodOptions { // preflight requests carry no credentials
+			return
 		}
-		c = 0
-		if stat.OpLatencies.Commands.Ops > 0 {
-			c = float64(stat.OpLatencies.Commands.Latency) / float64(stat.OpLatencies.Commands.Ops) / 1000
+		auth := s.auth
+		if isMutating(r) && s.adminAuth != "" {
+			auth = s.adminAuth
 		}
-		docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-			"\t"+strconv.FormatFloat(r, 'E', -1, 64)+
-			"\t"+strconv.FormatFloat(w, 'E', -1, 64)+
-			"\t"+strconv.FormatFloat(c, 'E', -1, 64))
-		// pstat = stat
-	}
-	return docs
-}
-
-// GetConnectionsTSV -
-func GetConnectionsTSV() []string {
-	var docs []string
-	var pstat mdb.ServerStatusDoc
-
-	docs = append(docs, "date\tCurrent\tAvailable\tCreated per minute")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
+		if isAuthorized(r, auth) == false {
+			if strings.Index(auth, ":") > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="keyhole"`)
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
 		}
-		if stat.Uptime > pstat.Uptime {
-			minutes := stat.LocalTime.Sub(pstat.LocalTime).Minutes()
-			churn := float64(stat.Connections.TotalCreated-pstat.Connections.TotalCreated) / minutes
-			docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-				"\t"+strconv.FormatInt(stat.Connections.Current, 10)+
-				"\t"+strconv.FormatInt(stat.Connections.Available, 10)+
-				"\t"+strconv.FormatInt(int64(churn), 10))
+		if token := r.URL.Query().Get("token"); token != "" && strings.Index(auth, ":") < 0 {
+			http.SetCookie(w, &http.Cookie{Name: tokenCookie, Value: token, Path: "/", HttpOnly: true,
+				SameSite: http.SameSiteStrictMode, Secure: s.tlsCert != ""})
 		}
-		pstat = stat
+		f(w, r)
 	}
+}
 
-	return docs
+// Handler returns handlers of all endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/grafana", s.secure(s.grafana.handler))
+	mux.HandleFunc("/grafana/", s.secure(s.grafana.handler))
+	mux.HandleFunc("/v1/series", s.secure(s.grafana.seriesHandler))
+	mux.HandleFunc("/v1/series/", s.secure(s.grafana.seriesHandler))
+	mux.HandleFunc("/", s.secure(handler))
+	return mux
 }
 
-// GetQueuesTSV -
-func GetQueuesTSV() []string {
-	var docs []string
-	docs = append(docs, "date\tActive Read\tActive Write\tQueued Read\tQueued Write")
-	for i, stat := range diagnosticData.ServerStatusList {
-		if len(diagnosticData.ServerStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
+// ListenAndServe listens to bind:port, with TLS if a certificate is set
+func (s *Server) ListenAndServe() error {
+	var err error
+	host := s.bind
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		if host, err = os.Hostname(); err != nil {
+			host = "localhost"
 		}
-		docs = append(docs, stat.LocalTime.Format("2006-01-02T15:04:05Z")+
-			"\t"+strconv.FormatInt(stat.GlobalLock.ActiveClients.Readers, 10)+
-			"\t"+strconv.FormatInt(stat.GlobalLock.ActiveClients.Writers, 10)+
-			"\t"+strconv.FormatInt(stat.GlobalLock.CurrentQueue.Readers, 10)+
-			"\t"+strconv.FormatInt(stat.GlobalLock.CurrentQueue.Writers, 10))
 	}
-
-	return docs
+	if s.auth == "" && s.adminAuth == "" && s.bind != "localhost" && s.bind != "127.0.0.1" && s.bind != "::1" {
+		log.Println("WARNING: web server is accessible without authentication")
+	}
+	addr := net.JoinHostPort(s.bind, strconv.Itoa(s.port))
+	server := &http.Server{Addr: addr, Handler: s.Handler()}
+	if s.tlsCert != "" {
+		log.Println("HTTPS server ready, URL: https://" + net.JoinHostPort(host, strconv.Itoa(s.port)) + "/")
+		return server.ListenAndServeTLS(s.tlsCert, s.tlsKey)
+	}
+	log.Println("HTTP server ready, URL: http://" + net.JoinHostPort(host, strconv.Itoa(s.port)) + "/")
+	return server.ListenAndServe()
 }
 
-// GetReplLagsTSV -
-func GetReplLagsTSV() []string {
-	var docs []string
-	var ts int64
-
-	str := "date"
-	for i, stat := range diagnosticData.ReplSetStatusList {
-		if len(diagnosticData.ReplSetStatusList) > base && frac > 0 && i%frac != 0 {
-			continue
-		}
-		ts = 0
-		sort.Slice(stat.Members, func(i, j int) bool { return stat.Members[i].Name < stat.Members[j].Name })
-		if i == 0 {
-			for _, mb := range stat.Members {
-				a := strings.Index(mb.Name, ".")
-				b := strings.LastIndex(mb.Name, ":")
-				if a < 0 || b < 0 {
-					str += "\t" + mb.Name
-				} else {
-					str += "\t" + mb.Name[0:a] + mb.Name[b:]
-				}
-			}
-			docs = append(docs, str)
-		}
-		for _, mb := range stat.Members {
-			if mb.State == 1 {
-				ts = mdb.GetOptime(mb.Optime)
-				break
-			}
-		}
-
-		str = stat.Date.Format("2006-01-02T15:04:05Z")
-		if ts == 0 {
-			continue
-		} else {
-			for _, mb := range stat.Members {
-				if mb.State == 2 { // SECONDARY
-					str += "\t" + strconv.FormatInt(int64(ts-mdb.GetOptime(mb.Optime)), 10)
-				} else if mb.State == 1 { // PRIMARY
-					str += "\t0"
-				}
-			}
-			docs = append(docs, str)
-		}
-	}
-	return docs
+// HTTPServer listens to localhost:port without authentication
+func HTTPServer(port int, g *Grafana) {
+	s := NewServer(g)
+	s.SetPort(port)
+	log.Fatal(s.ListenAndServe())
 }
@@ -0,0 +1,114 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerDashboard(t *testing.T) {
+	for _, path := range []string{"/", "/dashboard"} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || strings.Index(w.Body.String(), "grafana/query") < 0 {
+			t.Fatal(path, w.Code)
+		}
+	}
+}
+
+func TestHandlerPanelRedirect(t *testing.T) {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/latencies", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/#latencies" {
+		t.Fatal(w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	s := NewServer(getTestGrafana())
+	s.SetAuth("reader:secret")
+	s.SetAdminAuth("admin-token")
+	h := s.Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/series/list", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatal(w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/series/list", nil)
+	r.SetBasicAuth("reader", "secret")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/grafana/expressions", strings.NewReader(`{"name": "x", "expr": "1"}`))
+	r.SetBasicAuth("reader", "secret")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatal(w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/grafana/expressions", strings.NewReader(`{"name": "x", "expr": "1"}`))
+	r.Header.Set("Authorization", "Bearer admin-token")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatal(w.Code)
+	}
+}
+
+func TestServerDashboardToken(t *testing.T) {
+	s := NewServer(getTestGrafana())
+	s.SetAuth("reader-token")
+	h := s.Handler()
+	body := `{"range":{"from":"1970-01-01T00:00:00Z","to":"1970-01-01T01:00:00Z"},"targets":[{"target":"wt_cache_max","type":"timeserie"}]}`
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/grafana/query", strings.NewReader(body)))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatal(w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token=reader-token", nil))
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Name != tokenCookie || cookies[0].HttpOnly == false {
+		t.Fatal(w.Code, cookies)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/grafana/query", strings.NewReader(body)) // XHR of the dashboard
+	r.AddCookie(cookies[0])
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || strings.Index(w.Body.String(), "wt_cache_max") < 0 {
+		t.Fatal(w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token=wrong", nil))
+	if w.Code != http.StatusUnauthorized || len(w.Result().Cookies()) != 0 {
+		t.Fatal(w.Code)
+	}
+}
+
+func TestServerOrigins(t *testing.T) {
+	s := NewServer(getTestGrafana())
+	s.SetOrigins("http://grafana:3000, http://localhost:3000")
+	h := s.Handler()
+	for origin, allowed := range map[string]string{"http://grafana:3000": "http://grafana:3000", "http://evil.com": ""} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodOptions, "/grafana/query", nil)
+		r.Header.Set("Origin", origin)
+		h.ServeHTTP(w, r)
+		if w.Header().Get("Access-Control-Allow-Origin") != allowed {
+			t.Fatal(origin, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"sort"
+
+	"github.com/simagix/keyhole/sim/util"
+)
+
+// slow ops targets from log analytics
+const (
+	slowOpsAvgMilli         = "slowops_avg_ms"
+	slowOpsCount            = "slowops_count"
+	slowOpsMaxMilli         = "slowops_max_ms"
+	slowOpsPatternsAvgMilli = "slowops_patterns_avg_ms"
+	slowOpsPatternsCount    = "slowops_patterns_count"
+	slowOpsTopPatterns      = 10
+)
+
+// SetLogInfo sets time series of slow ops from log analytics
+func (g *Grafana) SetLogInfo(li *util.LogInfo) {
+	all := map[int64]util.OpBucketDoc{}
+	patterns := append([]util.OpPerformanceDoc{}, li.OpsPatterns...)
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].TotalMilli > patterns[j].TotalMilli })
+	var avgList, countList []TimeSeriesDoc
+	for i, doc := range patterns {
+		target := doc.Command + " " + doc.Namespace + " " + doc.Filter
+		avg := TimeSeriesDoc{Target: target, DataPoints: [][]float64{}}
+		count := TimeSeriesDoc{Target: target, DataPoints: [][]float64{}}
+		for _, t := range util.GetBucketTimes(doc.Buckets) {
+			bucket := doc.Buckets[t]
+			avg.DataPoints = append(avg.DataPoints, []float64{float64(bucket.TotalMilli) / float64(bucket.Count), float64(t * 1000)})
+			count.DataPoints = append(count.DataPoints, []float64{float64(bucket.Count), float64(t * 1000)})
+			b := all[t]
+			b.Count += bucket.Count
+			b.TotalMilli += bucket.TotalMilli
+			if bucket.MaxMilli > b.MaxMilli {
+				b.MaxMilli = bucket.MaxMilli
+			}
+			all[t] = b
+		}
+		if i < slowOpsTopPatterns {
+			avgList = append(avgList, avg)
+			countList = append(countList, count)
+		}
+	}
+	slowOps := map[string]TimeSeriesDoc{}
+	for _, name := range []string{slowOpsAvgMilli, slowOpsCount, slowOpsMaxMilli} {
+		slowOps[name] = TimeSeriesDoc{Target: name, DataPoints: [][]float64{}}
+	}
+	for _, t := range util.GetBucketTimes(all) {
+		b := all[t]
+		ms := float64(t * 1000)
+		for name, v := range map[string]float64{slowOpsAvgMilli: float64(b.TotalMilli) / float64(b.Count),
+			slowOpsCount: float64(b.Count), slowOpsMaxMilli: float64(b.MaxMilli)} {
+			data := slowOps[name]
+			data.DataPoints = append(data.DataPoints, []float64{v, ms})
+			slowOps[name] = data
+		}
+	}
+	g.Lock()
+	defer g.Unlock()
+	g.slowOps = slowOps
+	g.slowOpsPatterns = map[string][]TimeSeriesDoc{slowOpsPatternsAvgMilli: avgList, slowOpsPatternsCount: countList}
+}
+
+// getSlowOpsTargets returns names of slow ops targets if logs are loaded
+func (g *Grafana) getSlowOpsTargets() []string {
+	g.RLock()
+	defer g.RUnlock()
+	if g.slowOps == nil {
+		return []string{}
+	}
+	return []string{slowOpsAvgMilli, slowOpsCount, slowOpsMaxMilli, slowOpsPatternsAvgMilli, slowOpsPatternsCount}
+}
+
+// getSlowOpsTimeSeries returns slow ops series of a target, slowops_patterns_* expand to one per pattern
+func (g *Grafana) getSlowOpsTimeSeries(target string) ([]TimeSeriesDoc, bool) {
+	g.RLock()
+	defer g.RUnlock()
+	if data, ok := g.slowOps[target]; ok {
+		return []TimeSeriesDoc{data}, true
+	}
+	list, ok := g.slowOpsPatterns[target]
+	return list, ok
+}
@@ -0,0 +1,36 @@
+// Copyright 2019 Kuei-chun Chen. All rights reserved.
+
+package web
+
+import (
+	"testing"
+
+	"github.com/simagix/keyhole/sim/util"
+)
+
+func TestSetLogInfo(t *testing.T) {
+	grafana := NewGrafana()
+	li := util.NewLogInfo("mongod.log")
+	li.OpsPatterns = []util.OpPerformanceDoc{
+		util.OpPerformanceDoc{Command: "find", Namespace: "db.a", TotalMilli: 600, Count: 3,
+			Buckets: map[int64]util.OpBucketDoc{60: util.OpBucketDoc{Count: 2, TotalMilli: 500, MaxMilli: 400}, 120: util.OpBucketDoc{Count: 1, TotalMilli: 100, MaxMilli: 100}}},
+		util.OpPerformanceDoc{Command: "count", Namespace: "db.b", TotalMilli: 100, Count: 1,
+			Buckets: map[int64]util.OpBucketDoc{60: util.OpBucketDoc{Count: 1, TotalMilli: 100, MaxMilli: 100}}},
+	}
+	grafana.SetLogInfo(li)
+	if len(grafana.getSlowOpsTargets()) != 5 {
+		t.Fatal(grafana.getSlowOpsTargets())
+	}
+	list, _ := grafana.getTimeSeries(grafana.detailFTDC, "slowops_count")
+	if len(list) != 1 || len(list[0].DataPoints) != 2 || list[0].DataPoints[0][0] != 3 || list[0].DataPoints[0][1] != 60000 {
+		t.Fatal(list)
+	}
+	list, _ = grafana.getTimeSeries(grafana.detailFTDC, "slowops_max_ms")
+	if list[0].DataPoints[0][0] != 400 {
+		t.Fatal(list)
+	}
+	list, _ = grafana.getTimeSeries(grafana.detailFTDC, "slowops_patterns_avg_ms")
+	if len(list) != 2 || list[0].Target != "find db.a " || list[0].DataPoints[0][0] != 250 {
+		t.Fatal(list)
+	}
+}
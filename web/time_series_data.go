@@ -29,16 +29,24 @@ type RangeDoc struct {
 
 // TargetDoc -
 type TargetDoc struct {
-	Target string `json:"target"`
-	RefID  string `json:"refId"`
-	Type   string `json:"type"`
+	Target string        `json:"target"`
+	RefID  string        `json:"refId"`
+	Type   string        `json:"type"`
+	Data   TargetDataDoc `json:"data"`
+}
+
+// TargetDataDoc is the additional JSON data of a target, e.g. {"downsample": "lttb"}
+type TargetDataDoc struct {
+	Downsample string `json:"downsample"`
 }
 
 // QueryRequest -
 type QueryRequest struct {
-	Timezone string      `json:"timezone"`
-	Range    RangeDoc    `json:"range"`
-	Targets  []TargetDoc `json:"targets"`
+	Timezone      string      `json:"timezone"`
+	Range         RangeDoc    `json:"range"`
+	Targets       []TargetDoc `json:"targets"`
+	MaxDataPoints int         `json:"maxDataPoints"`
+	Downsample    string      `json:"downsample"`
 }
 
 var serverStatusChartsLegends = []string{
@@ -52,7 +60,8 @@ var wiredTigerChartsLegends = []string{
 	"scan_keys", "scan_objects", "scan_sort",
 	"wt_cache_max", "wt_cache_used", "wt_cache_dirty",
 	"wt_modified_evicted", "wt_unmodified_evicted", "wt_read_in_cache", "wt_written_from_cache",
-	"ticket_avail_read", "ticket_avail_write",
+	"ticket_avail_read", "ticket_avail_write", "ticket_out_read", "ticket_out_write", "ticket_total_read", "ticket_total_write",
+	"docs_returned", "oplat_reads_us", "oplat_reads_ops", "oplat_writes_us", "oplat_writes_ops", "oplat_commands_us", "oplat_commands_ops",
 }
 var systemMetricsChartsLegends = []string{
 	"cpu_idle", "cpu_iowait", "cpu_nice", "cpu_softirq", "cpu_steal", "cpu_system", "cpu_user",
@@ -63,8 +72,11 @@ var replSetChartsLegends = []string{"replication_lags"}
 // grafana-cli plugins install grafana-simple-json-datasource
 type Grafana struct {
 	sync.RWMutex
-	summaryFTDC FTDCStats
-	detailFTDC  FTDCStats
+	expressions     map[string]string
+	slowOps         map[string]TimeSeriesDoc
+	slowOpsPatterns map[string][]TimeSeriesDoc
+	summaryFTDC     FTDCStats
+	detailFTDC      FTDCStats
 }
 
 // FTDCStats FTDC stats
@@ -85,7 +97,10 @@ var g Grafana
 
 // NewGrafana -
 func NewGrafana() *Grafana {
-	g = Grafana{}
+	g = Grafana{expressions: map[string]string{}}
+	for k, v := range builtInExpressions {
+		g.expressions[k] = v
+	}
 	return &g
 }
 
@@ -411,6 +426,22 @@ func initWiredTigerTimeSeriesDoc(serverStatusList []mdb.ServerStatusDoc) map[str
 			x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.WiredTiger.ConcurrentTransactions.Write.Available), t))
 			timeSeriesData["ticket_avail_write"] = x
 
+			x = timeSeriesData["ticket_out_read"]
+			x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.WiredTiger.ConcurrentTransactions.Read.Out), t))
+			timeSeriesData["ticket_out_read"] = x
+
+			x = timeSeriesData["ticket_out_write"]
+			x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.WiredTiger.ConcurrentTransactions.Write.Out), t))
+			timeSeriesData["ticket_out_write"] = x
+
+			x = timeSeriesData["ticket_total_read"]
+			x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.WiredTiger.ConcurrentTransactions.Read.TotalTickets), t))
+			timeSeriesData["ticket_total_read"] = x
+
+			x = timeSeriesData["ticket_total_write"]
+			x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.WiredTiger.ConcurrentTransactions.Write.TotalTickets), t))
+			timeSeriesData["ticket_total_write"] = x
+
 			r := 0.0
 			if stat.OpLatencies.Reads.Ops > 0 {
 				r = float64(stat.OpLatencies.Reads.Latency) / float64(stat.OpLatencies.Reads.Ops) / 1000
@@ -446,6 +477,34 @@ func initWiredTigerTimeSeriesDoc(serverStatusList []mdb.ServerStatusDoc) map[str
 				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.Metrics.QueryExecutor.ScannedObjects-pstat.Metrics.QueryExecutor.ScannedObjects), t))
 				timeSeriesData["scan_objects"] = x
 
+				x = timeSeriesData["docs_returned"]
+				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.Metrics.Document.Returned-pstat.Metrics.Document.Returned), t))
+				timeSeriesData["docs_returned"] = x
+
+				x = timeSeriesData["oplat_reads_us"]
+				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.OpLatencies.Reads.Latency-pstat.OpLatencies.Reads.Latency), t))
+				timeSeriesData["oplat_reads_us"] = x
+
+				x = timeSeriesData["oplat_reads_ops"]
+				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.OpLatencies.Reads.Ops-pstat.OpLatencies.Reads.Ops), t))
+				timeSeriesData["oplat_reads_ops"] = x
+
+				x = timeSeriesData["oplat_writes_us"]
+				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.OpLatencies.Writes.Latency-pstat.OpLatencies.Writes.Latency), t))
+				timeSeriesData["oplat_writes_us"] = x
+
+				x = timeSeriesData["oplat_writes_ops"]
+				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.OpLatencies.Writes.Ops-pstat.OpLatencies.Writes.Ops), t))
+				timeSeriesData["oplat_writes_ops"] = x
+
+				x = timeSeriesData["oplat_commands_us"]
+				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.OpLatencies.Commands.Latency-pstat.OpLatencies.Commands.Latency), t))
+				timeSeriesData["oplat_commands_us"] = x
+
+				x = timeSeriesData["oplat_commands_ops"]
+				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.OpLatencies.Commands.Ops-pstat.OpLatencies.Commands.Ops), t))
+				timeSeriesData["oplat_commands_ops"] = x
+
 				x = timeSeriesData["scan_sort"]
 				x.DataPoints = append(x.DataPoints, getDataPoint(float64(stat.Metrics.Operation.ScanAndOrder-pstat.Metrics.Operation.ScanAndOrder), t))
 				timeSeriesData["scan_sort"] = x